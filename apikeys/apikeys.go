@@ -0,0 +1,402 @@
+// Package apikeys creates, lists, invalidates and rotates Elasticsearch API keys through
+// the security API, e.g. to hand services read-only keys scoped to the products index.
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+
+	"Elastic-Search/eserrors"
+	"Elastic-Search/logging"
+	"Elastic-Search/retry"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Operation names used for retry policies and errors
+const (
+	opCreate     = "create api key"
+	opList       = "list api keys"
+	opInvalidate = "invalidate api keys"
+)
+
+// IndexPrivileges grants privileges on the indices matching Names
+type IndexPrivileges struct {
+	Names      []string `json:"names"`
+	Privileges []string `json:"privileges"`
+}
+
+// RoleDescriptor limits what a key may do. A key without role descriptors gets a snapshot
+// of the privileges of the user that created it.
+type RoleDescriptor struct {
+	Cluster []string          `json:"cluster,omitempty"`
+	Indices []IndexPrivileges `json:"indices,omitempty"`
+}
+
+// ReadOnly returns role descriptors that only allow searching and reading indices
+func ReadOnly(indices ...string) map[string]RoleDescriptor {
+	return map[string]RoleDescriptor{
+		"read_only": {
+			Indices: []IndexPrivileges{{
+				Names:      indices,
+				Privileges: []string{"read", "view_index_metadata"},
+			}},
+		},
+	}
+}
+
+// CreateRequest describes a key to create
+type CreateRequest struct {
+	Name            string
+	Expiration      time.Duration // 0 creates a key that never expires
+	RoleDescriptors map[string]RoleDescriptor
+	Metadata        map[string]interface{}
+}
+
+// Key is a newly created key. Encoded is the credential to configure as api_key and is
+// only returned once, at creation.
+type Key struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Encoded    string     `json:"encoded"`
+	Expiration *time.Time `json:"expiration,omitempty"`
+}
+
+// Info describes an existing key without its secret
+type Info struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Username    string                 `json:"username"`
+	Realm       string                 `json:"realm"`
+	Creation    time.Time              `json:"creation"`
+	Expiration  *time.Time             `json:"expiration,omitempty"`
+	Invalidated bool                   `json:"invalidated"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Active reports whether the key can still authenticate at t
+func (i Info) Active(t time.Time) bool {
+	return !i.Invalidated && (i.Expiration == nil || i.Expiration.After(t))
+}
+
+// Filter selects the keys returned by List
+type Filter struct {
+	Name       string // Key name, wildcards allowed; empty matches every name
+	ActiveOnly bool   // Skip invalidated and expired keys
+	OwnedOnly  bool   // Only keys of the authenticated user
+}
+
+// Manager manages the API keys of a cluster. The credentials of its client need the
+// manage_own_api_key privilege, or manage_api_key to see and invalidate keys of other users.
+type Manager struct {
+	client *elasticsearch.Client
+	retry  retry.Policies
+	logger *slog.Logger
+}
+
+// NewManager creates a Manager sending its requests through client
+func NewManager(client *elasticsearch.Client, logger *slog.Logger) *Manager {
+	return &Manager{client: client, retry: retry.DefaultPolicies(), logger: logger}
+}
+
+func (m *Manager) log() *slog.Logger {
+	return logging.OrDefault(m.logger)
+}
+
+// do runs a security API call under the retry policy of op and decodes its response into v
+func (m *Manager) do(
+	ctx context.Context,
+	op string,
+	idempotent bool,
+	v interface{},
+	fn func(ctx context.Context) (*esapi.Response, error),
+) error {
+	res, err := retry.Do(ctx, m.retry.For(op, idempotent), fn)
+	if err != nil {
+		return fmt.Errorf("error executing %s: %w", op, err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			m.log().WarnContext(ctx, "error closing body", logging.KeyError, err)
+		}
+	}(res.Body)
+
+	if err := eserrors.FromResponse(op, res); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("error parsing %s response: %w", op, err)
+	}
+	return nil
+}
+
+// Create creates a key. It is never retried: a lost response would leave a duplicate key behind.
+func (m *Manager) Create(ctx context.Context, req CreateRequest) (*Key, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("%s: a name is required", opCreate)
+	}
+	// The expiration is sent in whole seconds, a shorter one would become 0s
+	if req.Expiration != 0 && req.Expiration < time.Second {
+		return nil, fmt.Errorf("%s: expiration %s must be 0 or at least a second", opCreate, req.Expiration)
+	}
+	body := map[string]interface{}{"name": req.Name}
+	if req.Expiration > 0 {
+		body["expiration"] = strconv.FormatInt(int64(req.Expiration/time.Second), 10) + "s"
+	}
+	if len(req.RoleDescriptors) > 0 {
+		body["role_descriptors"] = req.RoleDescriptors
+	}
+	if len(req.Metadata) > 0 {
+		body["metadata"] = req.Metadata
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling api key: %w", err)
+	}
+
+	var response struct {
+		ID         string      `json:"id"`
+		Name       string      `json:"name"`
+		Encoded    string      `json:"encoded"`
+		Expiration epochMillis `json:"expiration"`
+	}
+	err = m.do(ctx, opCreate, false, &response, func(ctx context.Context) (*esapi.Response, error) {
+		return m.client.Security.CreateAPIKey(
+			bytes.NewReader(data),
+			m.client.Security.CreateAPIKey.WithContext(ctx),
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.log().InfoContext(ctx, "created api key", "id", response.ID, "name", response.Name)
+	return &Key{
+		ID:         response.ID,
+		Name:       response.Name,
+		Encoded:    response.Encoded,
+		Expiration: response.Expiration.time(),
+	}, nil
+}
+
+// List returns the keys matching filter, newest first
+func (m *Manager) List(ctx context.Context, filter Filter) ([]Info, error) {
+	var response struct {
+		APIKeys []struct {
+			ID          string                 `json:"id"`
+			Name        string                 `json:"name"`
+			Username    string                 `json:"username"`
+			Realm       string                 `json:"realm"`
+			Creation    epochMillis            `json:"creation"`
+			Expiration  epochMillis            `json:"expiration"`
+			Invalidated bool                   `json:"invalidated"`
+			Metadata    map[string]interface{} `json:"metadata"`
+		} `json:"api_keys"`
+	}
+	err := m.do(ctx, opList, true, &response, func(ctx context.Context) (*esapi.Response, error) {
+		options := []func(*esapi.SecurityGetAPIKeyRequest){
+			m.client.Security.GetAPIKey.WithContext(ctx),
+			m.client.Security.GetAPIKey.WithActiveOnly(filter.ActiveOnly),
+		}
+		if filter.Name != "" {
+			options = append(options, m.client.Security.GetAPIKey.WithName(filter.Name))
+		}
+		if filter.OwnedOnly {
+			options = append(options, m.client.Security.GetAPIKey.WithOwner(true))
+		}
+		return m.client.Security.GetAPIKey(options...)
+	})
+	if errors.Is(err, eserrors.ErrNotFound) {
+		// Some versions answer 404 instead of an empty list when no key matches
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	keys := make([]Info, 0, len(response.APIKeys))
+	for _, key := range response.APIKeys {
+		info := Info{
+			ID:          key.ID,
+			Name:        key.Name,
+			Username:    key.Username,
+			Realm:       key.Realm,
+			Invalidated: key.Invalidated,
+			Expiration:  key.Expiration.time(),
+			Metadata:    key.Metadata,
+		}
+		if created := key.Creation.time(); created != nil {
+			info.Creation = *created
+		}
+		// active_only is only understood by recent clusters
+		if filter.ActiveOnly && !info.Active(now) {
+			continue
+		}
+		keys = append(keys, info)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].Creation.After(keys[j].Creation)
+	})
+	return keys, nil
+}
+
+// Invalidate invalidates keys by id and returns the ids that were invalidated.
+// Keys that were already invalid are skipped.
+func (m *Manager) Invalidate(ctx context.Context, ids ...string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(map[string]interface{}{"ids": ids})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling api key ids: %w", err)
+	}
+
+	var response struct {
+		Invalidated []string `json:"invalidated_api_keys"`
+		ErrorCount  int      `json:"error_count"`
+		Errors      []struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error_details"`
+	}
+	// Invalidating twice has the same effect as once
+	err = m.do(ctx, opInvalidate, true, &response, func(ctx context.Context) (*esapi.Response, error) {
+		return m.client.Security.InvalidateAPIKey(
+			bytes.NewReader(data),
+			m.client.Security.InvalidateAPIKey.WithContext(ctx),
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response.ErrorCount > 0 {
+		reason := ""
+		if len(response.Errors) > 0 {
+			reason = ": " + response.Errors[0].Reason
+		}
+		return response.Invalidated, fmt.Errorf("%s: %d of %d keys failed%s", opInvalidate, response.ErrorCount, len(ids), reason)
+	}
+	m.log().InfoContext(ctx, "invalidated api keys", "ids", response.Invalidated)
+	return response.Invalidated, nil
+}
+
+// Rotate creates a new key for req.Name unless an active key with that name is valid for
+// longer than renewBefore. It returns nil when no rotation was needed. The keys it replaces
+// stay valid until they expire or are retired, so clients can switch over first.
+func (m *Manager) Rotate(ctx context.Context, req CreateRequest, renewBefore time.Duration) (*Key, error) {
+	keys, err := m.List(ctx, Filter{Name: req.Name, ActiveOnly: true, OwnedOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(renewBefore)
+	for _, key := range keys {
+		if key.Expiration == nil || key.Expiration.After(deadline) {
+			return nil, nil
+		}
+	}
+	return m.Create(ctx, req)
+}
+
+// Retire invalidates the active keys named name except keep, typically the key returned by Rotate
+func (m *Manager) Retire(ctx context.Context, name, keep string) ([]string, error) {
+	keys, err := m.List(ctx, Filter{Name: name, ActiveOnly: true, OwnedOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, key := range keys {
+		if key.ID != keep {
+			ids = append(ids, key.ID)
+		}
+	}
+	return m.Invalidate(ctx, ids...)
+}
+
+// KeyRotation is the part of Manager a Rotator uses
+type KeyRotation interface {
+	Rotate(ctx context.Context, req CreateRequest, renewBefore time.Duration) (*Key, error)
+	Retire(ctx context.Context, name, keep string) ([]string, error)
+}
+
+// Rotator keeps a key fresh in the background: before the current key expires it creates a
+// new one, hands it to OnRotate and then retires the keys it replaced.
+type Rotator struct {
+	Manager     KeyRotation   // Usually a *Manager
+	Request     CreateRequest // Request.Expiration should be set, keys that never expire are never rotated
+	RenewBefore time.Duration // Rotate when the newest key expires within this window
+	Interval    time.Duration // How often to check, defaults to a minute
+	// OnRotate switches the clients to the new key. When it fails the old keys are kept and
+	// OnRotate is called again with the same key on the next check.
+	OnRotate func(ctx context.Context, key *Key) error
+	Logger   *slog.Logger // Optional: Logs failed checks, defaults to slog.Default()
+
+	// pending is the new key until the clients switched to it and the old keys are retired.
+	// Rotate sees the new key as fresh, so the handoff has to be finished from here.
+	pending  *Key
+	switched bool // OnRotate succeeded for pending
+}
+
+// Run checks the key every Interval until ctx is cancelled. Failed checks are logged and retried.
+func (r *Rotator) Run(ctx context.Context) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.check(ctx); err != nil && ctx.Err() == nil {
+			logging.OrDefault(r.Logger).ErrorContext(ctx, "api key rotation failed", "name", r.Request.Name, logging.KeyError, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Rotator) check(ctx context.Context) error {
+	if r.pending == nil {
+		key, err := r.Manager.Rotate(ctx, r.Request, r.RenewBefore)
+		if err != nil || key == nil {
+			return err
+		}
+		r.pending, r.switched = key, false
+	}
+
+	key := r.pending
+	if !r.switched && r.OnRotate != nil {
+		if err := r.OnRotate(ctx, key); err != nil {
+			return fmt.Errorf("error switching to api key %s: %w", key.ID, err)
+		}
+	}
+	r.switched = true
+	if _, err := r.Manager.Retire(ctx, r.Request.Name, key.ID); err != nil {
+		return err
+	}
+	r.pending = nil
+	return nil
+}
+
+// epochMillis decodes the millisecond timestamps of the security API
+type epochMillis int64
+
+func (e epochMillis) time() *time.Time {
+	if e == 0 {
+		return nil
+	}
+	t := time.UnixMilli(int64(e)).UTC()
+	return &t
+}
@@ -0,0 +1,374 @@
+package apikeys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Elastic-Search/eserrors"
+	"Elastic-Search/internal/estest"
+)
+
+// fakeSecurityAPI answers the API key endpoints with canned responses and records the requests
+type fakeSecurityAPI struct {
+	status   int
+	body     string
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []map[string]interface{}
+}
+
+func (f *fakeSecurityAPI) RoundTrip(r *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var body map[string]interface{}
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+	f.requests = append(f.requests, r)
+	f.bodies = append(f.bodies, body)
+
+	return estest.Response(r, f.status, f.body), nil
+}
+
+func newTestManager(t *testing.T, api *fakeSecurityAPI) *Manager {
+	t.Helper()
+	client := estest.NewClient(t, api)
+	m := NewManager(client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	m.retry.Default.InitialBackoff = time.Millisecond
+	return m
+}
+
+func TestCreate(t *testing.T) {
+	api := &fakeSecurityAPI{
+		status: http.StatusOK,
+		body:   `{"id": "VuaCfGcBCdbkQm-e5aOx", "name": "search-service", "encoded": "VnVhQ2ZHY0JDZGJrUW0tZTVhT3g6dWkybHAyYXhUTm1zeWFrdzl0dk5udw==", "expiration": 1700003600000}`,
+	}
+	m := newTestManager(t, api)
+
+	key, err := m.Create(context.Background(), CreateRequest{
+		Name:            "search-service",
+		Expiration:      time.Hour,
+		RoleDescriptors: ReadOnly("products"),
+		Metadata:        map[string]interface{}{"service": "search"},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if key.ID != "VuaCfGcBCdbkQm-e5aOx" || key.Name != "search-service" || key.Encoded == "" {
+		t.Errorf("Create() = %+v, want the key of the response", key)
+	}
+	if want := time.UnixMilli(1700003600000).UTC(); key.Expiration == nil || !key.Expiration.Equal(want) {
+		t.Errorf("Expiration = %v, want %v", key.Expiration, want)
+	}
+
+	if len(api.requests) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(api.requests))
+	}
+	if r := api.requests[0]; r.Method != http.MethodPut || r.URL.Path != "/_security/api_key" {
+		t.Errorf("request = %s %s, want PUT /_security/api_key", r.Method, r.URL.Path)
+	}
+	var want map[string]interface{}
+	_ = json.Unmarshal([]byte(`{
+		"name": "search-service",
+		"expiration": "3600s",
+		"role_descriptors": {"read_only": {"indices": [{"names": ["products"], "privileges": ["read", "view_index_metadata"]}]}},
+		"metadata": {"service": "search"}
+	}`), &want)
+	if got, _ := json.Marshal(api.bodies[0]); string(got) != mustMarshal(t, want) {
+		t.Errorf("body = %s, want %s", got, mustMarshal(t, want))
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCreateWithoutExpiration(t *testing.T) {
+	api := &fakeSecurityAPI{status: http.StatusOK, body: `{"id": "1", "name": "reporting", "encoded": "MTpzZWNyZXQ="}`}
+	m := newTestManager(t, api)
+
+	key, err := m.Create(context.Background(), CreateRequest{Name: "reporting"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if key.Expiration != nil {
+		t.Errorf("Expiration = %v, want none", key.Expiration)
+	}
+	if want := map[string]interface{}{"name": "reporting"}; mustMarshal(t, api.bodies[0]) != mustMarshal(t, want) {
+		t.Errorf("body = %v, want %v", api.bodies[0], want)
+	}
+}
+
+func TestCreateErrors(t *testing.T) {
+	api := &fakeSecurityAPI{status: http.StatusServiceUnavailable, body: `{"error": {"type": "cluster_block_exception", "reason": "blocked"}, "status": 503}`}
+	m := newTestManager(t, api)
+
+	if _, err := m.Create(context.Background(), CreateRequest{}); err == nil {
+		t.Error("Create() without a name succeeded")
+	}
+	for _, expiration := range []time.Duration{-time.Hour, time.Millisecond, 999 * time.Millisecond} {
+		if _, err := m.Create(context.Background(), CreateRequest{Name: "search-service", Expiration: expiration}); err == nil {
+			t.Errorf("Create() with expiration %s succeeded", expiration)
+		}
+	}
+	if len(api.requests) != 0 {
+		t.Fatalf("invalid request sent %d requests", len(api.requests))
+	}
+
+	// A lost response could leave a duplicate key behind, so a failed create is not retried
+	_, err := m.Create(context.Background(), CreateRequest{Name: "search-service"})
+	if !errors.Is(err, eserrors.ErrUnavailable) {
+		t.Errorf("Create() error = %v, want %v", err, eserrors.ErrUnavailable)
+	}
+	if len(api.requests) != 1 {
+		t.Errorf("sent %d requests, want 1", len(api.requests))
+	}
+}
+
+const listResponse = `{"api_keys": [
+	{"id": "old", "name": "search-service", "username": "elastic", "realm": "native", "creation": 1700000000000, "expiration": 1700003600000, "invalidated": false},
+	{"id": "new", "name": "search-service", "username": "elastic", "realm": "native", "creation": 1700001000000, "invalidated": false, "metadata": {"service": "search"}},
+	{"id": "revoked", "name": "search-service", "username": "elastic", "realm": "native", "creation": 1700002000000, "invalidated": true}
+]}`
+
+func TestList(t *testing.T) {
+	api := &fakeSecurityAPI{status: http.StatusOK, body: listResponse}
+	m := newTestManager(t, api)
+
+	keys, err := m.List(context.Background(), Filter{Name: "search-*", OwnedOnly: true})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var ids []string
+	for _, key := range keys {
+		ids = append(ids, key.ID)
+	}
+	if want := []string{"revoked", "new", "old"}; !slices.Equal(ids, want) {
+		t.Errorf("List() = %v, want newest first %v", ids, want)
+	}
+	if want := time.UnixMilli(1700000000000).UTC(); !keys[2].Creation.Equal(want) || keys[2].Expiration == nil {
+		t.Errorf("key = %+v, want creation %v and an expiration", keys[2], want)
+	}
+	if keys[1].Expiration != nil || keys[1].Metadata["service"] != "search" {
+		t.Errorf("key = %+v, want no expiration and the metadata", keys[1])
+	}
+
+	query := api.requests[0].URL.Query()
+	if api.requests[0].URL.Path != "/_security/api_key" || query.Get("name") != "search-*" || query.Get("owner") != "true" {
+		t.Errorf("request = %s, want the name and owner filters", api.requests[0].URL)
+	}
+}
+
+func TestListActiveOnly(t *testing.T) {
+	api := &fakeSecurityAPI{status: http.StatusOK, body: listResponse}
+	m := newTestManager(t, api)
+
+	// Clusters that ignore active_only still return the expired and invalidated keys
+	keys, err := m.List(context.Background(), Filter{ActiveOnly: true})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0].ID != "new" {
+		t.Errorf("List() = %+v, want only the active key", keys)
+	}
+	if got := api.requests[0].URL.Query().Get("active_only"); got != "true" {
+		t.Errorf("active_only = %q, want true", got)
+	}
+}
+
+func TestListNotFound(t *testing.T) {
+	api := &fakeSecurityAPI{status: http.StatusNotFound, body: `{"api_keys": [], "count": 0}`}
+	m := newTestManager(t, api)
+
+	keys, err := m.List(context.Background(), Filter{Name: "missing"})
+	if err != nil || len(keys) != 0 {
+		t.Errorf("List() = %v, %v, want no keys and no error", keys, err)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	api := &fakeSecurityAPI{status: http.StatusOK, body: `{"invalidated_api_keys": ["a", "b"], "previously_invalidated_api_keys": [], "error_count": 0}`}
+	m := newTestManager(t, api)
+
+	ids, err := m.Invalidate(context.Background(), "a", "b")
+	if err != nil {
+		t.Fatalf("Invalidate() error = %v", err)
+	}
+	if want := []string{"a", "b"}; !slices.Equal(ids, want) {
+		t.Errorf("Invalidate() = %v, want %v", ids, want)
+	}
+	if r := api.requests[0]; r.Method != http.MethodDelete || mustMarshal(t, api.bodies[0]) != `{"ids":["a","b"]}` {
+		t.Errorf("request = %s with %v, want DELETE with the ids", r.Method, api.bodies[0])
+	}
+
+	if ids, err := m.Invalidate(context.Background()); err != nil || ids != nil || len(api.requests) != 1 {
+		t.Errorf("Invalidate() of no ids = %v, %v after %d requests, want nothing sent", ids, err, len(api.requests))
+	}
+}
+
+func TestInvalidatePartialFailure(t *testing.T) {
+	api := &fakeSecurityAPI{status: http.StatusOK, body: `{
+		"invalidated_api_keys": ["a"],
+		"error_count": 1,
+		"error_details": [{"type": "exception", "reason": "failed to invalidate api key [b]"}]
+	}`}
+	m := newTestManager(t, api)
+
+	ids, err := m.Invalidate(context.Background(), "a", "b")
+	if want := []string{"a"}; !slices.Equal(ids, want) {
+		t.Errorf("Invalidate() = %v, want the keys that were invalidated %v", ids, want)
+	}
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 keys failed: failed to invalidate api key [b]") {
+		t.Errorf("Invalidate() error = %v, want the failed count and reason", err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	tests := []struct {
+		name       string
+		expiration time.Time
+		wantCreate bool
+	}{
+		{"fresh key", time.Now().Add(24 * time.Hour), false},
+		{"key about to expire", time.Now().Add(time.Minute), true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api := &fakeSecurityAPI{status: http.StatusOK, body: mustMarshal(t, map[string]interface{}{
+				"api_keys": []interface{}{map[string]interface{}{
+					"id":         "current",
+					"name":       "search-service",
+					"creation":   time.Now().Add(-time.Hour).UnixMilli(),
+					"expiration": test.expiration.UnixMilli(),
+				}},
+			})}
+			m := newTestManager(t, api)
+
+			// The fake answers the create request with the list response, so only the request matters
+			_, err := m.Rotate(context.Background(), CreateRequest{Name: "search-service", Expiration: 24 * time.Hour}, time.Hour)
+			if err != nil {
+				t.Fatalf("Rotate() error = %v", err)
+			}
+			created := len(api.requests) == 2 && api.requests[1].Method == http.MethodPut
+			if created != test.wantCreate {
+				t.Errorf("created a key: %v, want %v", created, test.wantCreate)
+			}
+		})
+	}
+}
+
+// fakeManager hands out numbered keys and records the keys kept by Retire
+type fakeManager struct {
+	rotations int
+	fresh     bool // The newest key is valid for longer than RenewBefore
+	retireErr error
+	kept      []string
+}
+
+func (f *fakeManager) Rotate(context.Context, CreateRequest, time.Duration) (*Key, error) {
+	if f.fresh {
+		return nil, nil
+	}
+	f.rotations++
+	// Like the cluster, the new key is fresh from now on
+	f.fresh = true
+	return &Key{ID: fmt.Sprintf("key-%d", f.rotations), Name: "search-service"}, nil
+}
+
+func (f *fakeManager) Retire(_ context.Context, _ string, keep string) ([]string, error) {
+	if f.retireErr != nil {
+		return nil, f.retireErr
+	}
+	f.kept = append(f.kept, keep)
+	return []string{"old"}, nil
+}
+
+func TestRotatorRetriesFailedHandoff(t *testing.T) {
+	manager := &fakeManager{}
+	var switched []string
+	failures := 1
+	rotator := &Rotator{
+		Manager: manager,
+		Request: CreateRequest{Name: "search-service", Expiration: time.Hour},
+		OnRotate: func(_ context.Context, key *Key) error {
+			switched = append(switched, key.ID)
+			if failures > 0 {
+				failures--
+				return errors.New("clients unreachable")
+			}
+			return nil
+		},
+	}
+	ctx := context.Background()
+
+	if err := rotator.check(ctx); err == nil {
+		t.Fatal("check() succeeded although OnRotate failed")
+	}
+	if len(manager.kept) != 0 {
+		t.Fatalf("old keys retired after a failed handoff: %v", manager.kept)
+	}
+
+	if err := rotator.check(ctx); err != nil {
+		t.Fatalf("second check() error = %v", err)
+	}
+	if manager.rotations != 1 {
+		t.Errorf("created %d keys, want 1", manager.rotations)
+	}
+	if want := []string{"key-1", "key-1"}; !slices.Equal(switched, want) {
+		t.Errorf("OnRotate called with %v, want %v", switched, want)
+	}
+	if want := []string{"key-1"}; !slices.Equal(manager.kept, want) {
+		t.Errorf("Retire kept %v, want %v", manager.kept, want)
+	}
+
+	// The handoff is done, the next check has nothing to do
+	if err := rotator.check(ctx); err != nil {
+		t.Fatalf("third check() error = %v", err)
+	}
+	if len(switched) != 2 || len(manager.kept) != 1 {
+		t.Errorf("idle check switched %v and retired %v", switched, manager.kept)
+	}
+}
+
+func TestRotatorRetriesFailedRetire(t *testing.T) {
+	manager := &fakeManager{retireErr: errors.New("cluster unavailable")}
+	calls := 0
+	rotator := &Rotator{
+		Manager: manager,
+		Request: CreateRequest{Name: "search-service", Expiration: time.Hour},
+		OnRotate: func(context.Context, *Key) error {
+			calls++
+			return nil
+		},
+	}
+	ctx := context.Background()
+
+	if err := rotator.check(ctx); err == nil {
+		t.Fatal("check() succeeded although Retire failed")
+	}
+	manager.retireErr = nil
+	if err := rotator.check(ctx); err != nil {
+		t.Fatalf("second check() error = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("OnRotate called %d times, want 1", calls)
+	}
+	if want := []string{"key-1"}; !slices.Equal(manager.kept, want) {
+		t.Errorf("Retire kept %v, want %v", manager.kept, want)
+	}
+}
@@ -0,0 +1,350 @@
+// Package breaker implements a client side circuit breaker and in-flight request cap
+// that wraps the HTTP transport of the Elasticsearch clients.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// State is the state of a circuit breaker
+type State int
+
+const (
+	// Closed lets every request through and tracks the error rate
+	Closed State = iota
+	// Open fails every request immediately until OpenTimeout has passed
+	Open
+	// HalfOpen lets a limited number of probe requests through to test recovery
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Sentinel errors matched by *RejectedError through errors.Is
+var (
+	ErrOpen            = errors.New("circuit breaker is open")
+	ErrTooManyInFlight = errors.New("too many requests in flight")
+)
+
+// RejectedError is returned without contacting Elasticsearch when the breaker sheds a request
+type RejectedError struct {
+	Reason     error         // ErrOpen or ErrTooManyInFlight
+	State      State         // Breaker state when the request was rejected
+	RetryAfter time.Duration // Time until the breaker lets probe requests through, if open
+}
+
+func (e *RejectedError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%v (state %s, retry after %s)", e.Reason, e.State, e.RetryAfter.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("%v (state %s)", e.Reason, e.State)
+}
+
+func (e *RejectedError) Unwrap() error {
+	return e.Reason
+}
+
+// Config configures when the breaker trips and how it recovers
+type Config struct {
+	Window              time.Duration // Length of the window the error rate is computed over
+	MinRequests         int           // Requests needed in a window before the breaker can trip
+	ErrorRateThreshold  float64       // Fraction of failed requests that trips the breaker, between 0 and 1
+	LatencyThreshold    time.Duration // Requests slower than this count as failures; 0 disables
+	OpenTimeout         time.Duration // Time spent open before probing with half-open requests
+	HalfOpenMaxRequests int           // Successful probes needed to close the breaker again
+	MaxInFlight         int           // Concurrent requests allowed through; 0 means unlimited
+}
+
+// DefaultConfig trips at a 50% error rate over 10 seconds and caps in-flight requests at 64
+func DefaultConfig() Config {
+	return Config{
+		Window:              10 * time.Second,
+		MinRequests:         20,
+		ErrorRateThreshold:  0.5,
+		LatencyThreshold:    5 * time.Second,
+		OpenTimeout:         30 * time.Second,
+		HalfOpenMaxRequests: 3,
+		MaxInFlight:         64,
+	}
+}
+
+// Stats is a snapshot of the breaker counters
+type Stats struct {
+	State       State
+	Requests    int // Requests completed in the current window
+	Failures    int // Failed or slow requests in the current window
+	InFlight    int
+	Rejected    int64 // Requests shed since the breaker was created
+	LastTripped time.Time
+}
+
+// Breaker is a circuit breaker shared by all requests going through its transport
+type Breaker struct {
+	config Config
+	now    func() time.Time
+
+	mu            sync.Mutex
+	state         State
+	windowStart   time.Time
+	requests      int
+	failures      int
+	openedAt      time.Time
+	probes        int // Half-open requests started
+	probeSuccess  int // Half-open requests that succeeded
+	rejected      int64
+	onStateChange []func(from, to State)
+
+	inFlight chan struct{}
+}
+
+// New creates a closed breaker. Zero fields of config fall back to DefaultConfig.
+func New(config Config) *Breaker {
+	defaults := DefaultConfig()
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.MinRequests <= 0 {
+		config.MinRequests = defaults.MinRequests
+	}
+	if config.ErrorRateThreshold <= 0 {
+		config.ErrorRateThreshold = defaults.ErrorRateThreshold
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = defaults.OpenTimeout
+	}
+	if config.HalfOpenMaxRequests <= 0 {
+		config.HalfOpenMaxRequests = defaults.HalfOpenMaxRequests
+	}
+
+	b := &Breaker{
+		config: config,
+		now:    time.Now,
+	}
+	b.windowStart = b.now()
+	if config.MaxInFlight > 0 {
+		b.inFlight = make(chan struct{}, config.MaxInFlight)
+	}
+	return b
+}
+
+// State returns the current state, moving from open to half-open once OpenTimeout has passed
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.state
+}
+
+// Stats returns a snapshot of the breaker counters
+func (b *Breaker) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return Stats{
+		State:       b.state,
+		Requests:    b.requests,
+		Failures:    b.failures,
+		InFlight:    len(b.inFlight),
+		Rejected:    b.rejected,
+		LastTripped: b.openedAt,
+	}
+}
+
+// OnStateChange registers a callback run on every state transition.
+// Callbacks run while the breaker is locked and must not call back into it.
+func (b *Breaker) OnStateChange(fn func(from, to State)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onStateChange = append(b.onStateChange, fn)
+}
+
+// Transport wraps next (http.DefaultTransport when nil) so every request goes through the breaker
+func (b *Breaker) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{breaker: b, next: next}
+}
+
+type transport struct {
+	breaker *Breaker
+	next    http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.breaker
+
+	if b.inFlight != nil {
+		select {
+		case b.inFlight <- struct{}{}:
+			defer func() { <-b.inFlight }()
+		default:
+			return nil, b.reject(ErrTooManyInFlight)
+		}
+	}
+
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+
+	start := b.now()
+	res, err := t.next.RoundTrip(req)
+	switch {
+	case b.slow(b.now().Sub(start)):
+		b.record(true)
+	case abandoned(err):
+		b.release()
+	default:
+		b.record(failed(res, err))
+	}
+	return res, err
+}
+
+// abandoned reports whether the caller cancelled the request or its deadline passed.
+// Unless it was slow, such a request says nothing about the cluster, so it counts as
+// neither a failure nor a success.
+func abandoned(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// failed reports whether a response means the cluster is struggling.
+// Client errors such as 404 or 409 say nothing about cluster health.
+func failed(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError
+}
+
+func (b *Breaker) slow(latency time.Duration) bool {
+	return b.config.LatencyThreshold > 0 && latency > b.config.LatencyThreshold
+}
+
+// allow decides whether a request may be sent in the current state
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+
+	switch b.state {
+	case Open:
+		b.rejected++
+		return &RejectedError{
+			Reason:     ErrOpen,
+			State:      Open,
+			RetryAfter: b.config.OpenTimeout - b.now().Sub(b.openedAt),
+		}
+	case HalfOpen:
+		if b.probes >= b.config.HalfOpenMaxRequests {
+			b.rejected++
+			return &RejectedError{Reason: ErrOpen, State: HalfOpen}
+		}
+		b.probes++
+	}
+	return nil
+}
+
+func (b *Breaker) reject(reason error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rejected++
+	return &RejectedError{Reason: reason, State: b.state}
+}
+
+// release frees the probe taken by an abandoned request so another request can probe
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == HalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// record accounts for a finished request and trips or closes the breaker
+func (b *Breaker) record(failure bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+
+	switch b.state {
+	case HalfOpen:
+		if failure {
+			b.transition(Open)
+			return
+		}
+		b.probeSuccess++
+		if b.probeSuccess >= b.config.HalfOpenMaxRequests {
+			b.transition(Closed)
+		}
+	case Closed:
+		b.requests++
+		if failure {
+			b.failures++
+		}
+		if b.requests >= b.config.MinRequests &&
+			float64(b.failures)/float64(b.requests) >= b.config.ErrorRateThreshold {
+			b.transition(Open)
+		}
+	}
+}
+
+// advance applies time based transitions: a new counting window while closed,
+// and half-open once the open timeout has passed. Callers hold b.mu.
+func (b *Breaker) advance() {
+	now := b.now()
+	switch b.state {
+	case Closed:
+		if now.Sub(b.windowStart) >= b.config.Window {
+			b.resetWindow(now)
+		}
+	case Open:
+		if now.Sub(b.openedAt) >= b.config.OpenTimeout {
+			b.transition(HalfOpen)
+		}
+	}
+}
+
+func (b *Breaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.requests = 0
+	b.failures = 0
+}
+
+// transition moves to a new state and notifies listeners. Callers hold b.mu.
+func (b *Breaker) transition(to State) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+
+	now := b.now()
+	switch to {
+	case Open:
+		b.openedAt = now
+	case HalfOpen:
+		b.probes = 0
+		b.probeSuccess = 0
+	case Closed:
+		b.resetWindow(now)
+	}
+
+	for _, fn := range b.onStateChange {
+		fn(from, to)
+	}
+}
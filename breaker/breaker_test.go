@@ -0,0 +1,257 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// roundTripFunc answers requests with a function
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func failWith(err error) http.RoundTripper {
+	return roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, err
+	})
+}
+
+func respondWith(status int) http.RoundTripper {
+	return roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: status, Body: http.NoBody, Request: r}, nil
+	})
+}
+
+func send(t *testing.T, transport http.RoundTripper) error {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, "http://localhost:9200", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := transport.RoundTrip(req)
+	if res != nil && res.Body != nil {
+		_ = res.Body.Close()
+	}
+	return err
+}
+
+func TestTripsOnErrorRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport http.RoundTripper
+		wantTrip  bool
+	}{
+		{"connection refused", failWith(errors.New("dial tcp: connection refused")), true},
+		{"server error", respondWith(http.StatusServiceUnavailable), true},
+		{"too many requests", respondWith(http.StatusTooManyRequests), true},
+		{"not found", respondWith(http.StatusNotFound), false},
+		{"conflict", respondWith(http.StatusConflict), false},
+		{"ok", respondWith(http.StatusOK), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := New(Config{MinRequests: 4, ErrorRateThreshold: 0.5})
+			transport := b.Transport(test.transport)
+
+			for range 4 {
+				_ = send(t, transport)
+			}
+			if tripped := b.State() == Open; tripped != test.wantTrip {
+				t.Fatalf("tripped = %v, want %v (stats %+v)", tripped, test.wantTrip, b.Stats())
+			}
+			if !test.wantTrip {
+				return
+			}
+
+			err := send(t, transport)
+			var rejected *RejectedError
+			if !errors.As(err, &rejected) || !errors.Is(err, ErrOpen) || rejected.RetryAfter <= 0 {
+				t.Errorf("error = %v, want a rejection with a retry after", err)
+			}
+			if b.Stats().Rejected != 1 {
+				t.Errorf("rejected = %d, want 1", b.Stats().Rejected)
+			}
+		})
+	}
+}
+
+func TestMinRequests(t *testing.T) {
+	b := New(Config{MinRequests: 5, ErrorRateThreshold: 0.5})
+	transport := b.Transport(respondWith(http.StatusInternalServerError))
+
+	for range 4 {
+		_ = send(t, transport)
+	}
+	if b.State() != Closed {
+		t.Errorf("state = %s after fewer than MinRequests, want closed", b.State())
+	}
+}
+
+func TestHalfOpenRecovery(t *testing.T) {
+	tests := []struct {
+		name      string
+		probe     http.RoundTripper
+		wantState State
+	}{
+		{"successful probes close", respondWith(http.StatusOK), Closed},
+		{"failed probe reopens", respondWith(http.StatusServiceUnavailable), Open},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			now := time.Now()
+			b := New(Config{MinRequests: 1, ErrorRateThreshold: 0.5, OpenTimeout: time.Second, HalfOpenMaxRequests: 2})
+			b.now = func() time.Time { return now }
+			var transitions []State
+			b.OnStateChange(func(from, to State) { transitions = append(transitions, to) })
+
+			_ = send(t, b.Transport(respondWith(http.StatusServiceUnavailable)))
+			now = now.Add(time.Second)
+			if b.State() != HalfOpen {
+				t.Fatalf("state after the open timeout = %s, want half-open", b.State())
+			}
+
+			for range 2 {
+				if err := send(t, b.Transport(test.probe)); err != nil && test.wantState == Closed {
+					t.Fatalf("probe rejected: %v", err)
+				}
+			}
+			if b.State() != test.wantState {
+				t.Errorf("state = %s, want %s (transitions %v)", b.State(), test.wantState, transitions)
+			}
+		})
+	}
+}
+
+func TestHalfOpenLimitsProbes(t *testing.T) {
+	now := time.Now()
+	b := New(Config{MinRequests: 1, ErrorRateThreshold: 0.5, OpenTimeout: time.Second, HalfOpenMaxRequests: 1})
+	b.now = func() time.Time { return now }
+
+	_ = send(t, b.Transport(respondWith(http.StatusServiceUnavailable)))
+	now = now.Add(time.Second)
+
+	// The probe is still running when the next request arrives
+	probing := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if err := send(t, b.Transport(respondWith(http.StatusOK))); !errors.Is(err, ErrOpen) {
+			t.Errorf("request during the probe: error = %v, want %v", err, ErrOpen)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	})
+	if err := send(t, b.Transport(probing)); err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+	if b.State() != Closed {
+		t.Errorf("state = %s, want closed", b.State())
+	}
+}
+
+func TestSlowRequestFails(t *testing.T) {
+	now := time.Now()
+	b := New(Config{MinRequests: 1, ErrorRateThreshold: 0.5, LatencyThreshold: time.Second})
+	b.now = func() time.Time { return now }
+
+	slow := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		now = now.Add(2 * time.Second)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	})
+	_ = send(t, b.Transport(slow))
+	if b.State() != Open {
+		t.Errorf("state = %s, want open after a slow request", b.State())
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	b := New(Config{MaxInFlight: 1})
+
+	// The second request arrives while the first one holds the only slot
+	var inner error
+	busy := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		inner = send(t, b.Transport(respondWith(http.StatusOK)))
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	})
+	if err := send(t, b.Transport(busy)); err != nil {
+		t.Fatalf("first request rejected: %v", err)
+	}
+	if !errors.Is(inner, ErrTooManyInFlight) {
+		t.Errorf("second request error = %v, want %v", inner, ErrTooManyInFlight)
+	}
+	if stats := b.Stats(); stats.InFlight != 0 || stats.Rejected != 1 {
+		t.Errorf("stats = %+v, want no request in flight and 1 rejected", stats)
+	}
+}
+
+func TestAbandonedRequestsDoNotTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantTrip bool
+	}{
+		{"cancelled", context.Canceled, false},
+		{"deadline exceeded", fmt.Errorf("dial tcp: %w", context.DeadlineExceeded), false},
+		{"connection refused", errors.New("dial tcp: connection refused"), true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := New(Config{MinRequests: 2, ErrorRateThreshold: 0.5})
+			transport := b.Transport(failWith(test.err))
+
+			for range 5 {
+				_ = send(t, transport)
+			}
+			if tripped := b.State() == Open; tripped != test.wantTrip {
+				t.Errorf("tripped = %v, want %v (stats %+v)", tripped, test.wantTrip, b.Stats())
+			}
+			if !test.wantTrip && b.Stats().Requests != 0 {
+				t.Errorf("counted %d abandoned requests", b.Stats().Requests)
+			}
+		})
+	}
+}
+
+func TestAbandonedProbeIsReleased(t *testing.T) {
+	now := time.Now()
+	b := New(Config{MinRequests: 1, ErrorRateThreshold: 0.5, OpenTimeout: time.Second, HalfOpenMaxRequests: 1})
+	b.now = func() time.Time { return now }
+
+	_ = send(t, b.Transport(failWith(errors.New("connection refused"))))
+	if b.State() != Open {
+		t.Fatalf("state = %s, want open", b.State())
+	}
+	now = now.Add(time.Second)
+
+	// The cancelled probe leaves its place to the next request, which closes the breaker
+	_ = send(t, b.Transport(failWith(context.Canceled)))
+	if b.State() != HalfOpen {
+		t.Fatalf("state after a cancelled probe = %s, want half-open", b.State())
+	}
+	ok := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	})
+	if err := send(t, b.Transport(ok)); err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+	if b.State() != Closed {
+		t.Errorf("state = %s, want closed", b.State())
+	}
+}
+
+func TestSlowAbandonedRequestFails(t *testing.T) {
+	now := time.Now()
+	b := New(Config{MinRequests: 1, ErrorRateThreshold: 0.5, LatencyThreshold: time.Second})
+	b.now = func() time.Time { return now }
+
+	slow := roundTripFunc(func(*http.Request) (*http.Response, error) {
+		now = now.Add(2 * time.Second)
+		return nil, context.DeadlineExceeded
+	})
+	_ = send(t, b.Transport(slow))
+	if b.State() != Open {
+		t.Errorf("state = %s, want open after a slow request", b.State())
+	}
+}
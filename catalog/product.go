@@ -0,0 +1,49 @@
+// Package catalog holds the documents of the products index shared by the seeder, the
+// search client and the servers. The index mapping is generated from their tags.
+package catalog
+
+import (
+	"time"
+
+	"Elastic-Search/docstore"
+	"Elastic-Search/mapping"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// Names of the analysis components referenced by the Product mapping. The seed package
+// defines them in the index settings.
+const (
+	EnglishAnalyzer      = "english_stemmed"
+	AutocompleteAnalyzer = "autocomplete"
+	SynonymAnalyzer      = "product_synonym_search"
+	KeywordNormalizer    = "lowercase_keyword"
+)
+
+// Product represents a product in the catalog
+type Product struct {
+	ID          string    `json:"id" es:"keyword"`
+	Name        string    `json:"name" es:"text,analyzer=english_stemmed,search_analyzer=product_synonym_search,fields.keyword=keyword,fields.keyword.ignore_above=256,fields.autocomplete=text,fields.autocomplete.analyzer=autocomplete,fields.autocomplete.search_analyzer=standard"`
+	Description string    `json:"description" es:"text,analyzer=english_stemmed,search_analyzer=product_synonym_search"`
+	Price       float64   `json:"price" es:"float"`
+	Categories  []string  `json:"categories" es:"keyword"`
+	Brand       string    `json:"brand" es:"keyword,normalizer=lowercase_keyword"`
+	InStock     bool      `json:"in_stock" es:"boolean"`
+	Rating      float64   `json:"rating" es:"float"`
+	CreatedAt   time.Time `json:"created_at" es:"date"`
+}
+
+// ProductMapping is the mapping of the products index
+var ProductMapping = mapping.MustFor(Product{})
+
+// NewProductRepository creates a repository of the products in config.Index, "products" by default
+func NewProductRepository(client *elasticsearch.Client, config docstore.Config[Product]) (*docstore.Repository[Product], error) {
+	config.ID = func(product Product) string { return product.ID }
+	if config.Index == "" {
+		config.Index = "products"
+	}
+	if config.Name == "" {
+		config.Name = "product"
+	}
+	return docstore.New(client, config)
+}
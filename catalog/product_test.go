@@ -0,0 +1,79 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"Elastic-Search/docstore"
+	"Elastic-Search/internal/estest"
+)
+
+// fakeProducts keeps the documents created through it and returns them on get
+type fakeProducts struct {
+	paths []string
+	docs  map[string]json.RawMessage
+}
+
+func (f *fakeProducts) RoundTrip(r *http.Request) (*http.Response, error) {
+	f.paths = append(f.paths, r.Method+" "+r.URL.Path)
+	status, body := http.StatusNotFound, `{"found": false}`
+	_, id, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/products/"), "/")
+	switch {
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/products/_create/"):
+		source, _ := io.ReadAll(r.Body)
+		f.docs[id] = source
+		status, body = http.StatusCreated, `{"_id": "`+id+`", "_seq_no": 0, "_primary_term": 1, "result": "created"}`
+	case r.Method == http.MethodGet && f.docs[id] != nil:
+		status, body = http.StatusOK, `{"_id": "`+id+`", "_seq_no": 0, "_primary_term": 1, "found": true, "_source": `+string(f.docs[id])+`}`
+	}
+
+	return estest.Response(r, status, body), nil
+}
+
+func TestProductRepositoryRoundTrip(t *testing.T) {
+	transport := &fakeProducts{docs: map[string]json.RawMessage{}}
+	client := estest.NewClient(t, transport)
+	products, err := NewProductRepository(client, docstore.Config[Product]{
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	product := Product{
+		ID:         "42",
+		Name:       "Ultra Laptop",
+		Price:      999.5,
+		Categories: []string{"Laptops", "Gaming"},
+		Brand:      "Asus",
+		InStock:    true,
+		Rating:     4.5,
+		CreatedAt:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if _, err := products.Create(context.Background(), product); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	got, version, err := products.Get(context.Background(), "42")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.ID != product.ID || got.Name != product.Name || got.Price != product.Price ||
+		len(got.Categories) != 2 || !got.CreatedAt.Equal(product.CreatedAt) {
+		t.Errorf("Get() = %+v, want %+v", got, product)
+	}
+	if version != (docstore.Version{SeqNo: 0, PrimaryTerm: 1}) {
+		t.Errorf("Get() version = %+v, want 0-1", version)
+	}
+
+	// The id comes from the product and the index defaults to products
+	want := []string{"PUT /products/_create/42", "GET /products/_doc/42"}
+	if strings.Join(transport.paths, ", ") != strings.Join(want, ", ") {
+		t.Errorf("sent %v, want %v", transport.paths, want)
+	}
+}
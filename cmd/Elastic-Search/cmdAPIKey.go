@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"Elastic-Search/apikeys"
+)
+
+var apiKeyActions = []struct{ name, summary string }{
+	{"create", "Create a key, optionally read-only on some indices"},
+	{"list", "List keys"},
+	{"invalidate", "Invalidate keys by id or name"},
+	{"rotate", "Create a new key when the current one is about to expire"},
+}
+
+func apiKeyUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: Elastic-Search apikey <action> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Actions:")
+	for _, action := range apiKeyActions {
+		fmt.Fprintf(w, "  %-10s %s\n", action.name, action.summary)
+	}
+}
+
+func runAPIKey(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		apiKeyUsage(os.Stderr)
+		if len(args) == 0 {
+			return usagef("apikey: missing action")
+		}
+		return nil
+	}
+	action := args[0]
+
+	var o options
+	fs := o.flagSet("apikey "+action, "", 30*time.Second)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: Elastic-Search apikey %s [flags]\n", action)
+		fs.PrintDefaults()
+	}
+
+	var name, ids, readOnly, roles *string
+	var expiration, renewBefore *time.Duration
+	var active, mine, retire *bool
+	switch action {
+	case "create", "rotate":
+		name = fs.String("name", "", "Name of the key (required)")
+		expiration = fs.Duration("expiration", 30*24*time.Hour, "Lifetime of the key; 0 never expires")
+		readOnly = fs.String("read-only", "", "Comma separated indices the key may only read, e.g. products")
+		roles = fs.String("role-descriptors", "", "Role descriptors as JSON, @file or - for stdin")
+		if action == "rotate" {
+			renewBefore = fs.Duration("renew-before", 7*24*time.Hour, "Rotate when the newest key expires within this window")
+			retire = fs.Bool("retire", false, "Invalidate the replaced keys right away instead of letting them expire")
+		}
+	case "list":
+		name = fs.String("name", "", "Only keys with this name, wildcards allowed")
+		active = fs.Bool("active", false, "Only keys that are neither invalidated nor expired")
+		mine = fs.Bool("mine", false, "Only keys of the authenticated user")
+	case "invalidate":
+		ids = fs.String("id", "", "Comma separated key ids")
+		name = fs.String("name", "", "Invalidate every active key of the authenticated user with this name")
+	default:
+		apiKeyUsage(os.Stderr)
+		return usagef("apikey: unknown action %q", action)
+	}
+
+	if err := o.parse(fs, args[1:]); err != nil {
+		return err
+	}
+	switch {
+	case (action == "create" || action == "rotate") && *name == "":
+		return usagef("-name is required")
+	case action == "create" || action == "rotate":
+		if *readOnly != "" && *roles != "" {
+			return usagef("-read-only and -role-descriptors cannot be combined")
+		}
+		if *expiration < 0 {
+			return usagef("-expiration must not be negative")
+		}
+	case action == "invalidate" && (*ids == "") == (*name == ""):
+		return usagef("exactly one of -id and -name is required")
+	}
+
+	logger, err := o.logger(ctx)
+	if err != nil {
+		return err
+	}
+	client, err := o.esClient(ctx, newBreaker(logger), logger)
+	if err != nil {
+		return err
+	}
+	manager := apikeys.NewManager(client, logger)
+
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+
+	switch action {
+	case "create", "rotate":
+		req := apikeys.CreateRequest{Name: *name, Expiration: *expiration}
+		switch {
+		case *readOnly != "":
+			req.RoleDescriptors = apikeys.ReadOnly(strings.Split(*readOnly, ",")...)
+		case *roles != "":
+			if err := readJSON(*roles, &req.RoleDescriptors); err != nil {
+				return err
+			}
+		}
+		var key *apikeys.Key
+		if action == "create" {
+			key, err = manager.Create(ctx, req)
+		} else {
+			key, err = manager.Rotate(ctx, req, *renewBefore)
+		}
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			fmt.Fprintf(os.Stderr, "the newest %q key is valid for longer than %s, nothing to rotate\n", *name, *renewBefore)
+			return nil
+		}
+		if action == "rotate" && *retire {
+			if _, err := manager.Retire(ctx, key.Name, key.ID); err != nil {
+				return err
+			}
+		}
+		// The encoded key is only returned once, so it is the one secret this command prints
+		return render(os.Stdout, o.output, key, func() ([]string, [][]string) {
+			return []string{"ID", "NAME", "EXPIRATION", "ENCODED"}, [][]string{
+				{key.ID, key.Name, formatTime(key.Expiration), key.Encoded},
+			}
+		})
+	case "list":
+		keys, err := manager.List(ctx, apikeys.Filter{Name: *name, ActiveOnly: *active, OwnedOnly: *mine})
+		if err != nil {
+			return err
+		}
+		return render(os.Stdout, o.output, keys, func() ([]string, [][]string) {
+			now := time.Now()
+			rows := make([][]string, 0, len(keys))
+			for _, key := range keys {
+				rows = append(rows, []string{
+					key.ID,
+					key.Name,
+					key.Username,
+					key.Creation.Format(time.RFC3339),
+					formatTime(key.Expiration),
+					strconv.FormatBool(key.Active(now)),
+				})
+			}
+			return []string{"ID", "NAME", "OWNER", "CREATED", "EXPIRATION", "ACTIVE"}, rows
+		})
+	case "invalidate":
+		var invalidated []string
+		if *ids != "" {
+			invalidated, err = manager.Invalidate(ctx, strings.Split(*ids, ",")...)
+		} else {
+			invalidated, err = manager.Retire(ctx, *name, "")
+		}
+		if err != nil {
+			return err
+		}
+		return render(os.Stdout, o.output, map[string]interface{}{"invalidated": invalidated},
+			func() ([]string, [][]string) {
+				rows := make([][]string, 0, len(invalidated))
+				for _, id := range invalidated {
+					rows = append(rows, []string{id})
+				}
+				return []string{"INVALIDATED"}, rows
+			})
+	}
+	return nil
+}
+
+// formatTime formats an optional time, "never" when it is not set
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
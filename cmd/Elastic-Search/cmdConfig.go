@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"Elastic-Search/esconfig"
+)
+
+// runConfig prints the effective configuration after files, environment and flags are applied.
+// Secrets print as [REDACTED].
+func runConfig(_ context.Context, args []string) error {
+	var o options
+	fs := o.flagSet("config", "", 0)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: Elastic-Search config [flags]")
+		fs.PrintDefaults()
+	}
+	if err := o.parse(fs, args); err != nil {
+		return err
+	}
+
+	c := o.config
+	addresses := c.ClientAddresses()
+	if c.CloudID != "" {
+		// Validated by parse
+		address, _ := esconfig.CloudAddress(c.CloudID)
+		addresses = []string{address}
+	}
+	settings := [][]string{
+		{"addresses", strings.Join(addresses, ",")},
+		{"cloud_id", c.CloudID},
+		{"username", c.Username},
+		{"password", c.Password.String()},
+		{"api_key", c.APIKey.String()},
+		{"log_level", c.LogLevel},
+		{"metrics_addr", c.MetricsAddr},
+		{"slow_query", c.SlowQuery.String()},
+		{"tls.ca_file", c.TLS.CAFile},
+		{"tls.cert_file", c.TLS.CertFile},
+		{"tls.key_file", c.TLS.KeyFile},
+		{"tls.ca_fingerprint", c.TLS.Fingerprint},
+		{"tls.insecure", strconv.FormatBool(c.TLS.Insecure)},
+		{"transport.max_idle_conns_per_host", strconv.Itoa(c.Transport.MaxIdleConnsPerHost)},
+		{"transport.max_conns_per_host", strconv.Itoa(c.Transport.MaxConnsPerHost)},
+		{"transport.dial_timeout", c.Transport.DialTimeout.String()},
+		{"transport.response_header_timeout", c.Transport.ResponseHeaderTimeout.String()},
+		{"transport.idle_conn_timeout", c.Transport.IdleConnTimeout.String()},
+		{"transport.compress_requests", strconv.FormatBool(c.Transport.CompressRequests)},
+		{"discovery.on_start", strconv.FormatBool(c.Discovery.OnStart)},
+		{"discovery.interval", c.Discovery.Interval.String()},
+		{"startup.wait_timeout", c.Startup.WaitTimeout.String()},
+		{"startup.wait_for_status", c.Startup.WaitForStatus},
+	}
+	values := map[string]interface{}{}
+	for _, setting := range settings {
+		values[setting[0]] = setting[1]
+	}
+	values["addresses"] = addresses
+	values["tls.insecure"] = c.TLS.Insecure
+	values["transport.max_idle_conns_per_host"] = c.Transport.MaxIdleConnsPerHost
+	values["transport.max_conns_per_host"] = c.Transport.MaxConnsPerHost
+	values["transport.compress_requests"] = c.Transport.CompressRequests
+	values["discovery.on_start"] = c.Discovery.OnStart
+	return render(os.Stdout, o.output, values, func() ([]string, [][]string) {
+		return []string{"SETTING", "VALUE"}, settings
+	})
+}
@@ -0,0 +1,545 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"Elastic-Search/metrics"
+	"Elastic-Search/searches"
+)
+
+// maxHistory is the number of lines kept in the history file
+const maxHistory = 1000
+
+const replHelp = `Queries (results are shown for the current page):
+  match  <field> <text>              Full text search on one field
+  multi  <field,field...> <text>     Full text search across several fields
+  fuzzy  <field> <text>              Typo tolerant search, fuzziness AUTO
+  phrase <field> <text>              Phrase search, use phrase~N for a slop of N
+  range  <field> <op>=<value>...     Range with gt, gte, lt and/or lte, e.g. range price gte=10 lt=50
+  bool   <json>                      Bool clauses, e.g. bool {"must": [{"match": {"brand": "Apple"}}]}
+  agg    <json>                      Aggregations, e.g. agg {"brands": {"terms": {"field": "brand"}}}
+
+Commands:
+  :index [name]          Show or switch the index
+  :page [from] [size]    Show or set the page of hits
+  :last                  Show the body of the last request
+  :explain               Run the last query again and explain the score of each hit
+  :profile               Run the last query again and show where the time went
+  :history               List previous lines; !N runs line N again and !! the last one
+  :help                  Show this help
+  :quit                  Leave, as does Ctrl-D`
+
+// lastQuery is the request body of the most recent search
+type lastQuery struct {
+	kind  string
+	index string
+	body  []byte
+}
+
+// repl is an interactive session against one search client at a time
+type repl struct {
+	sc          *searches.SearchClient
+	out         io.Writer
+	timeout     time.Duration
+	page        searches.SearchParams
+	last        *lastQuery
+	history     []string
+	historyFile string
+}
+
+func runRepl(ctx context.Context, args []string) error {
+	var o options
+	fs := o.flagSet("repl", "products", 30*time.Second)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: Elastic-Search repl [flags]")
+		fs.PrintDefaults()
+	}
+	historyFile := fs.String("history", defaultHistoryFile(), "File keeping the lines entered; empty disables it")
+	if err := o.parse(fs, args); err != nil {
+		return err
+	}
+
+	logger, err := o.logger(ctx)
+	if err != nil {
+		return err
+	}
+	searchBreaker := newBreaker(logger)
+	client, err := o.esClient(ctx, searchBreaker, logger)
+	if err != nil {
+		return err
+	}
+
+	r := &repl{
+		out:         os.Stdout,
+		timeout:     o.timeout,
+		page:        searches.SearchParams{Size: 10},
+		historyFile: *historyFile,
+	}
+	r.sc = searches.NewSearchClient(client, searches.Config{
+		Index:     o.index,
+		Breaker:   searchBreaker,
+		Metrics:   metrics.Default,
+		Logger:    logger,
+		SlowQuery: o.config.SlowQuery,
+		OnQuery: func(kind, index string, body []byte) {
+			r.last = &lastQuery{kind: kind, index: index, body: body}
+		},
+	})
+	r.loadHistory()
+	return r.run(ctx, os.Stdin)
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".elastic-search_history")
+}
+
+// run reads lines from in until it is exhausted, :quit is entered or ctx is cancelled
+func (r *repl) run(ctx context.Context, in io.Reader) error {
+	// Lines are read in the background so Ctrl-C ends the session while waiting for input
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	fmt.Fprintln(r.out, `Type :help for the list of commands.`)
+	for {
+		fmt.Fprintf(r.out, "%s> ", r.sc.Index())
+		var line string
+		var ok bool
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(r.out)
+			return nil
+		case line, ok = <-lines:
+		}
+		if !ok {
+			fmt.Fprintln(r.out)
+			return nil
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "!") {
+			recalled, err := r.recall(line)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				continue
+			}
+			fmt.Fprintln(r.out, recalled)
+			line = recalled
+		}
+		r.remember(line)
+
+		if line == ":quit" || line == ":q" || line == ":exit" {
+			return nil
+		}
+		if err := r.eval(ctx, line); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+	}
+}
+
+// eval runs one line of input
+func (r *repl) eval(ctx context.Context, line string) error {
+	name, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimSpace(rest)
+
+	switch name {
+	case ":help", ":h", "help":
+		fmt.Fprintln(r.out, replHelp)
+		return nil
+	case ":index":
+		if rest != "" {
+			r.sc = r.sc.WithIndex(rest)
+		}
+		fmt.Fprintf(r.out, "index: %s\n", r.sc.Index())
+		return nil
+	case ":page":
+		return r.setPage(rest)
+	case ":history":
+		for i, entry := range r.history {
+			fmt.Fprintf(r.out, "%5d  %s\n", i+1, entry)
+		}
+		return nil
+	case ":last":
+		if r.last == nil {
+			return errors.New("no query has been run yet")
+		}
+		fmt.Fprintf(r.out, "POST /%s/_search (%s)\n", r.last.index, r.last.kind)
+		var body interface{}
+		if err := json.Unmarshal(r.last.body, &body); err != nil {
+			return err
+		}
+		return printJSON(r.out, body)
+	case ":explain":
+		return r.explain(ctx)
+	case ":profile":
+		return r.profile(ctx)
+	}
+	if strings.HasPrefix(name, ":") {
+		return fmt.Errorf("unknown command %s, see :help", name)
+	}
+	return r.search(ctx, name, rest)
+}
+
+// search runs one of the query kinds and prints its hits and aggregations
+func (r *repl) search(ctx context.Context, kind, args string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var result *searches.SearchResult
+	var err error
+	switch {
+	case kind == "match", kind == "fuzzy", kind == "multi", kind == "phrase", strings.HasPrefix(kind, "phrase~"):
+		field, text, _ := strings.Cut(args, " ")
+		text = strings.TrimSpace(text)
+		if field == "" || text == "" {
+			return fmt.Errorf("usage: %s <field> <text>", kind)
+		}
+		switch kind {
+		case "match":
+			result, err = r.sc.MatchSearch(ctx, field, text, r.page)
+		case "fuzzy":
+			result, err = r.sc.FuzzySearch(ctx, field, text, "AUTO", r.page)
+		case "multi":
+			result, err = r.sc.MultiMatchSearch(ctx, text, strings.Split(field, ","), r.page)
+		default:
+			slop := 0
+			if _, n, found := strings.Cut(kind, "~"); found {
+				if slop, err = strconv.Atoi(n); err != nil || slop < 0 {
+					return fmt.Errorf("invalid slop %q", n)
+				}
+			}
+			result, err = r.sc.PhraseSearch(ctx, field, text, slop, r.page)
+		}
+	case kind == "range":
+		fields := strings.Fields(args)
+		if len(fields) < 2 {
+			return errors.New("usage: range <field> <op>=<value>...")
+		}
+		ranges := map[string]interface{}{}
+		for _, bound := range fields[1:] {
+			op, value, _ := strings.Cut(bound, "=")
+			if op != "gt" && op != "gte" && op != "lt" && op != "lte" || value == "" {
+				return fmt.Errorf("invalid bound %q, expected gt, gte, lt or lte=<value>", bound)
+			}
+			ranges[op] = rangeBound(value)
+		}
+		result, err = r.sc.RangeSearch(ctx, fields[0], ranges, r.page)
+	case kind == "bool", kind == "agg":
+		var params map[string]interface{}
+		if err := json.Unmarshal([]byte(args), &params); err != nil {
+			return fmt.Errorf("usage: %s <json>: %w", kind, err)
+		}
+		if kind == "bool" {
+			result, err = r.sc.BoolSearch(ctx, params, r.page)
+		} else {
+			result, err = r.sc.AggregationSearch(ctx, params)
+		}
+	default:
+		return fmt.Errorf("unknown query kind %q, see :help", kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+	if len(result.Items) > 0 || kind != "agg" {
+		header, rows := productTable(result)
+		if err := printTable(r.out, header, rows); err != nil {
+			return err
+		}
+	}
+	if aggs, ok := result.Aggs.(map[string]interface{}); ok {
+		printTree(r.out, aggregationTree(aggs), "")
+	}
+	return nil
+}
+
+// explain replays the last query with score explanations
+func (r *repl) explain(ctx context.Context) error {
+	if r.last == nil {
+		return errors.New("no query to explain, run one first")
+	}
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	hits, err := r.sc.WithIndex(r.last.index).Explain(ctx, r.last.body)
+	if err != nil {
+		return err
+	}
+	if len(hits) == 0 {
+		fmt.Fprintln(r.out, "no hits")
+		return nil
+	}
+	nodes := make([]treeNode, 0, len(hits))
+	for _, hit := range hits {
+		nodes = append(nodes, treeNode{
+			label:    fmt.Sprintf("_id %s, score %.4f", hit.ID, hit.Score),
+			children: []treeNode{explanationTree(hit.Explanation)},
+		})
+	}
+	printTree(r.out, nodes, "")
+	return nil
+}
+
+// profile replays the last query with profiling
+func (r *repl) profile(ctx context.Context) error {
+	if r.last == nil {
+		return errors.New("no query to profile, run one first")
+	}
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	shards, err := r.sc.WithIndex(r.last.index).Profile(ctx, r.last.body)
+	if err != nil {
+		return err
+	}
+	nodes := make([]treeNode, 0, len(shards))
+	for _, shard := range shards {
+		node := treeNode{label: "shard " + shard.ID}
+		for _, search := range shard.Searches {
+			for _, query := range search.Query {
+				node.children = append(node.children, profileTree(query))
+			}
+			node.children = append(node.children, treeNode{
+				label: "rewrite " + time.Duration(search.RewriteTime).String(),
+			})
+			for _, collector := range search.Collector {
+				node.children = append(node.children, profileTree(collector))
+			}
+		}
+		for _, agg := range shard.Aggregations {
+			node.children = append(node.children, profileTree(agg))
+		}
+		nodes = append(nodes, node)
+	}
+	printTree(r.out, nodes, "")
+	return nil
+}
+
+func (r *repl) setPage(args string) error {
+	fields := strings.Fields(args)
+	if len(fields) > 2 {
+		return errors.New("usage: :page [from] [size]")
+	}
+	page := r.page
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid number %q", field)
+		}
+		if i == 0 {
+			page.From = n
+		} else {
+			page.Size = n
+		}
+	}
+	r.page = page
+	fmt.Fprintf(r.out, "from: %d, size: %d\n", r.page.From, r.page.Size)
+	return nil
+}
+
+func (r *repl) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, r.timeout)
+}
+
+// recall resolves !! and !N to a line of the history
+func (r *repl) recall(line string) (string, error) {
+	if len(r.history) == 0 {
+		return "", errors.New("history is empty")
+	}
+	if line == "!!" {
+		return r.history[len(r.history)-1], nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 || n > len(r.history) {
+		return "", fmt.Errorf("no history entry %s", line[1:])
+	}
+	return r.history[n-1], nil
+}
+
+// remember adds line to the history and appends it to the history file
+func (r *repl) remember(line string) {
+	if len(r.history) > 0 && r.history[len(r.history)-1] == line {
+		return
+	}
+	r.history = append(r.history, line)
+	if r.historyFile == "" {
+		return
+	}
+	f, err := os.OpenFile(r.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+	_, _ = fmt.Fprintln(f, line)
+}
+
+// loadHistory reads the last maxHistory lines of the history file and trims the file to them
+func (r *repl) loadHistory() {
+	if r.historyFile == "" {
+		return
+	}
+	data, err := os.ReadFile(r.historyFile)
+	if err != nil {
+		return
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return
+	}
+	if len(lines) > maxHistory {
+		lines = lines[len(lines)-maxHistory:]
+		_ = os.WriteFile(r.historyFile, []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+	}
+	r.history = lines
+}
+
+// aggregationTree turns aggregation results into a tree: buckets under their aggregation
+// and sub-aggregations under their bucket
+func aggregationTree(aggs map[string]interface{}) []treeNode {
+	names := make([]string, 0, len(aggs))
+	for name, agg := range aggs {
+		if _, ok := agg.(map[string]interface{}); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	nodes := make([]treeNode, 0, len(names))
+	for _, name := range names {
+		agg := aggs[name].(map[string]interface{})
+		switch buckets := agg["buckets"].(type) {
+		case []interface{}:
+			node := treeNode{label: name}
+			for _, b := range buckets {
+				if bucket, ok := b.(map[string]interface{}); ok {
+					node.children = append(node.children, bucketTree(bucketKey(bucket), bucket))
+				}
+			}
+			nodes = append(nodes, node)
+			continue
+		case map[string]interface{}:
+			// Keyed buckets, e.g. of filters aggregations
+			node := treeNode{label: name}
+			keys := make([]string, 0, len(buckets))
+			for key := range buckets {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if bucket, ok := buckets[key].(map[string]interface{}); ok {
+					node.children = append(node.children, bucketTree(key, bucket))
+				}
+			}
+			nodes = append(nodes, node)
+			continue
+		}
+		if value, ok := agg["value"]; ok {
+			nodes = append(nodes, treeNode{label: fmt.Sprintf("%s: %v", name, value)})
+			continue
+		}
+		if docCount, ok := agg["doc_count"]; ok {
+			// Single bucket aggregations such as filter or nested
+			node := bucketTree(name, agg)
+			node.label = fmt.Sprintf("%s (%v)", name, docCount)
+			nodes = append(nodes, node)
+			continue
+		}
+		// Multi value metrics such as stats or percentiles
+		compact, _ := json.Marshal(agg)
+		nodes = append(nodes, treeNode{label: fmt.Sprintf("%s: %s", name, compact)})
+	}
+	return nodes
+}
+
+// bucketTree labels a bucket with its key and document count and nests its sub-aggregations
+func bucketTree(key string, bucket map[string]interface{}) treeNode {
+	subAggs := make(map[string]interface{}, len(bucket))
+	for name, value := range bucket {
+		// The object key of composite buckets and the after_key of composite aggregations
+		// nested in single bucket aggregations are not sub-aggregations
+		if name != "key" && name != "after_key" {
+			subAggs[name] = value
+		}
+	}
+	return treeNode{
+		label:    fmt.Sprintf("%s (%v)", key, bucket["doc_count"]),
+		children: aggregationTree(subAggs),
+	}
+}
+
+// bucketKey formats the key of a bucket. Composite keys become their sources in order of
+// name, e.g. "brand=apple, category=laptops".
+func bucketKey(bucket map[string]interface{}) string {
+	if key, ok := bucket["key_as_string"]; ok {
+		return fmt.Sprint(key)
+	}
+	composite, ok := bucket["key"].(map[string]interface{})
+	if !ok {
+		return fmt.Sprint(bucket["key"])
+	}
+	sources := make([]string, 0, len(composite))
+	for source, value := range composite {
+		sources = append(sources, fmt.Sprintf("%s=%v", source, value))
+	}
+	sort.Strings(sources)
+	return strings.Join(sources, ", ")
+}
+
+func explanationTree(e searches.Explanation) treeNode {
+	node := treeNode{label: fmt.Sprintf("%.4f %s", e.Value, e.Description)}
+	for _, detail := range e.Details {
+		node.children = append(node.children, explanationTree(detail))
+	}
+	return node
+}
+
+func profileTree(p searches.ProfileNode) treeNode {
+	name := p.Type
+	if name == "" {
+		name = p.Name
+	}
+	description := p.Description
+	if description == "" {
+		description = p.Reason
+	}
+	if runes := []rune(description); len(runes) > 80 {
+		description = string(runes[:77]) + "..."
+	}
+	node := treeNode{label: fmt.Sprintf("%s %s [%s]", name, description, time.Duration(p.TimeInNanos))}
+	for _, child := range p.Children {
+		node.children = append(node.children, profileTree(child))
+	}
+	return node
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"Elastic-Search/searches"
+)
+
+func TestAggregationTree(t *testing.T) {
+	var aggs map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"by_brand_category": {
+			"after_key": {"brand": "dell", "category": "laptops"},
+			"buckets": [
+				{"key": {"brand": "apple", "category": "laptops"}, "doc_count": 3, "avg_price": {"value": 1200}},
+				{"key": {"brand": "dell", "category": "laptops"}, "doc_count": 2}
+			]
+		},
+		"in_stock": {
+			"doc_count": 5,
+			"pages": {
+				"after_key": {"brand": "hp"},
+				"buckets": [{"key": {"brand": "hp"}, "doc_count": 5}]
+			}
+		},
+		"by_day": {
+			"buckets": [{"key": 1714521600000, "key_as_string": "2024-05-01", "doc_count": 4}]
+		}
+	}`), &aggs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	printTree(&buf, aggregationTree(aggs), "")
+	want := strings.Join([]string{
+		"├── by_brand_category",
+		"│   ├── brand=apple, category=laptops (3)",
+		"│   │   └── avg_price: 1200",
+		"│   └── brand=dell, category=laptops (2)",
+		"├── by_day",
+		"│   └── 2024-05-01 (4)",
+		"└── in_stock (5)",
+		"    └── pages",
+		"        └── brand=hp (5)",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("aggregation tree =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestProfileTreeTruncatesByRune(t *testing.T) {
+	description := strings.Repeat("é", 100)
+	node := profileTree(searches.ProfileNode{Type: "TermQuery", Description: description, TimeInNanos: 1500})
+
+	if !utf8.ValidString(node.label) {
+		t.Fatalf("label %q is not valid UTF-8", node.label)
+	}
+	want := "TermQuery " + strings.Repeat("é", 77) + "... [1.5µs]"
+	if node.label != want {
+		t.Errorf("label = %q, want %q", node.label, want)
+	}
+}
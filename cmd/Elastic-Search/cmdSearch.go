@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"Elastic-Search/metrics"
+	"Elastic-Search/rpc"
+	"Elastic-Search/searches"
+)
+
+var searchKinds = []struct{ name, summary string }{
+	{"match", "Full text search on one field"},
+	{"multi", "Full text search across several fields"},
+	{"bool", "Bool query from JSON clauses"},
+	{"range", "Numeric or date range on one field"},
+	{"fuzzy", "Typo tolerant search on one field"},
+	{"phrase", "Phrase search on one field"},
+	{"agg", "Aggregations from JSON"},
+	{"examples", "Run one search of every kind and log the results"},
+	{"serve", "Serve the search API over HTTP and/or gRPC"},
+}
+
+func searchUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: Elastic-Search search <kind> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Kinds:")
+	for _, kind := range searchKinds {
+		fmt.Fprintf(w, "  %-9s %s\n", kind.name, kind.summary)
+	}
+}
+
+// searchFunc runs one search with the client of the command
+type searchFunc func(ctx context.Context, sc *searches.SearchClient, page searches.SearchParams) (*searches.SearchResult, error)
+
+func runSearch(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		searchUsage(os.Stderr)
+		if len(args) == 0 {
+			return usagef("search: missing kind")
+		}
+		return nil
+	}
+	kind := args[0]
+
+	var o options
+	fs := o.flagSet("search "+kind, "products", 30*time.Second)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: Elastic-Search search %s [flags]\n", kind)
+		fs.PrintDefaults()
+	}
+	from := fs.Int("from", 0, "Offset of the first hit")
+	size := fs.Int("size", 10, "Number of hits")
+	strict := fs.Bool("strict", false, "Fail instead of returning partial results when shards fail or time out")
+
+	var search searchFunc
+	var httpAddr, grpcAddr *string
+	switch kind {
+	case "match":
+		field := fs.String("field", "name", "Field to search")
+		query := fs.String("query", "", "Text to search for (required)")
+		search = func(ctx context.Context, sc *searches.SearchClient, page searches.SearchParams) (*searches.SearchResult, error) {
+			if *query == "" {
+				return nil, usagef("-query is required")
+			}
+			return sc.MatchSearch(ctx, *field, *query, page)
+		}
+	case "multi":
+		fields := fs.String("fields", "name,description", "Comma separated fields to search")
+		query := fs.String("query", "", "Text to search for (required)")
+		search = func(ctx context.Context, sc *searches.SearchClient, page searches.SearchParams) (*searches.SearchResult, error) {
+			if *query == "" {
+				return nil, usagef("-query is required")
+			}
+			return sc.MultiMatchSearch(ctx, *query, strings.Split(*fields, ","), page)
+		}
+	case "bool":
+		clauses := fs.String("query", "", `Bool clauses as JSON, @file or - for stdin, e.g. {"must": [{"match": {"brand": "Apple"}}]} (required)`)
+		search = func(ctx context.Context, sc *searches.SearchClient, page searches.SearchParams) (*searches.SearchResult, error) {
+			var params map[string]interface{}
+			if err := readJSON(*clauses, &params); err != nil {
+				return nil, err
+			}
+			return sc.BoolSearch(ctx, params, page)
+		}
+	case "range":
+		field := fs.String("field", "price", "Numeric or date field")
+		bounds := map[string]*string{
+			"gt":  fs.String("gt", "", "Exclusive lower bound"),
+			"gte": fs.String("gte", "", "Inclusive lower bound"),
+			"lt":  fs.String("lt", "", "Exclusive upper bound"),
+			"lte": fs.String("lte", "", "Inclusive upper bound"),
+		}
+		search = func(ctx context.Context, sc *searches.SearchClient, page searches.SearchParams) (*searches.SearchResult, error) {
+			ranges := map[string]interface{}{}
+			for op, bound := range bounds {
+				if *bound == "" {
+					continue
+				}
+				ranges[op] = rangeBound(*bound)
+			}
+			if len(ranges) == 0 {
+				return nil, usagef("at least one of -gt, -gte, -lt, -lte is required")
+			}
+			return sc.RangeSearch(ctx, *field, ranges, page)
+		}
+	case "fuzzy":
+		field := fs.String("field", "name", "Field to search")
+		query := fs.String("query", "", "Text to search for (required)")
+		fuzziness := fs.String("fuzziness", "AUTO", "Maximum edit distance: AUTO, 0, 1 or 2")
+		search = func(ctx context.Context, sc *searches.SearchClient, page searches.SearchParams) (*searches.SearchResult, error) {
+			if *query == "" {
+				return nil, usagef("-query is required")
+			}
+			var distance interface{} = *fuzziness
+			if *fuzziness != "AUTO" {
+				n, err := strconv.Atoi(*fuzziness)
+				if err != nil || n < 0 || n > 2 {
+					return nil, usagef("-fuzziness must be AUTO, 0, 1 or 2")
+				}
+				distance = n
+			}
+			return sc.FuzzySearch(ctx, *field, *query, distance, page)
+		}
+	case "phrase":
+		field := fs.String("field", "description", "Field to search")
+		query := fs.String("query", "", "Phrase to search for (required)")
+		slop := fs.Int("slop", 0, "Number of positions the words may move")
+		search = func(ctx context.Context, sc *searches.SearchClient, page searches.SearchParams) (*searches.SearchResult, error) {
+			if *query == "" {
+				return nil, usagef("-query is required")
+			}
+			return sc.PhraseSearch(ctx, *field, *query, *slop, page)
+		}
+	case "agg":
+		aggs := fs.String("aggs", "", `Aggregations as JSON, @file or - for stdin, e.g. {"avg_price": {"avg": {"field": "price"}}} (required)`)
+		search = func(ctx context.Context, sc *searches.SearchClient, _ searches.SearchParams) (*searches.SearchResult, error) {
+			var params map[string]interface{}
+			if err := readJSON(*aggs, &params); err != nil {
+				return nil, err
+			}
+			return sc.AggregationSearch(ctx, params)
+		}
+	case "examples":
+	case "serve":
+		httpAddr = fs.String("http", "", "Serve the REST search API on this address, e.g. :8080")
+		grpcAddr = fs.String("grpc", "", "Serve the gRPC search service on this address, e.g. :9090")
+	default:
+		searchUsage(os.Stderr)
+		return usagef("search: unknown kind %q", kind)
+	}
+
+	if err := o.parse(fs, args[1:]); err != nil {
+		return err
+	}
+	if *from < 0 || *size < 0 {
+		return usagef("-from and -size must not be negative")
+	}
+	if kind == "serve" && *httpAddr == "" && *grpcAddr == "" {
+		return usagef("at least one of -http and -grpc is required")
+	}
+
+	logger, err := o.logger(ctx)
+	if err != nil {
+		return err
+	}
+	searchBreaker := newBreaker(logger)
+	client, err := o.esClient(ctx, searchBreaker, logger)
+	if err != nil {
+		return err
+	}
+
+	config := searches.Config{
+		Index:     o.index,
+		Breaker:   searchBreaker,
+		Metrics:   metrics.Default,
+		Logger:    logger,
+		SlowQuery: o.config.SlowQuery,
+	}
+	if *strict {
+		config.PartialMode = searches.PartialResultsStrict
+	}
+	sc := searches.NewSearchClient(client, config)
+
+	switch kind {
+	case "serve":
+		return serveSearch(ctx, sc, logger, *httpAddr, *grpcAddr)
+	case "examples":
+		ctx, cancel := o.withTimeout(ctx)
+		defer cancel()
+		searches.RunExamples(ctx, sc, logger)
+		return nil
+	}
+
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+	result, err := search(ctx, sc, searches.SearchParams{From: *from, Size: *size})
+	if err != nil {
+		return err
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+	if kind == "agg" {
+		return render(os.Stdout, o.output, result, func() ([]string, [][]string) {
+			return aggregationTable(result.Aggs)
+		})
+	}
+	return render(os.Stdout, o.output, result, func() ([]string, [][]string) {
+		return productTable(result)
+	})
+}
+
+// serveSearch serves the REST API and/or the gRPC service until ctx is cancelled
+func serveSearch(ctx context.Context, sc *searches.SearchClient, logger *slog.Logger, httpAddr, grpcAddr string) error {
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	var servers []func() error
+	if httpAddr != "" {
+		servers = append(servers, func() error {
+			return searches.NewSearchServer(sc, logger).ListenAndServe(ctx, httpAddr)
+		})
+	}
+	if grpcAddr != "" {
+		servers = append(servers, func() error {
+			server := rpc.NewServer(logger)
+			rpc.RegisterSearchServiceServer(server, searches.NewSearchService(sc))
+			return rpc.ListenAndServe(ctx, server, grpcAddr, logger)
+		})
+	}
+	return rpc.ServeAll(stop, servers...)
+}
+
+// rangeBound sends numbers as numbers and anything else (dates, date math) as strings
+func rangeBound(bound string) interface{} {
+	if n, err := strconv.ParseFloat(bound, 64); err == nil && !math.IsInf(n, 0) && !math.IsNaN(n) {
+		return n
+	}
+	return bound
+}
+
+// readJSON decodes a JSON flag value into v. "@path" reads the file at path and "-" reads stdin.
+func readJSON(value string, v interface{}) error {
+	var data []byte
+	var err error
+	switch {
+	case value == "":
+		return usagef("a JSON value is required")
+	case value == "-":
+		data, err = io.ReadAll(os.Stdin)
+	case strings.HasPrefix(value, "@"):
+		data, err = os.ReadFile(value[1:])
+	default:
+		data = []byte(value)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading JSON input: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return usagef("invalid JSON input: %v", err)
+	}
+	return nil
+}
+
+func productTable(result *searches.SearchResult) ([]string, [][]string) {
+	rows := make([][]string, 0, len(result.Items)+1)
+	for _, product := range result.Items {
+		rows = append(rows, []string{
+			product.ID,
+			product.Name,
+			product.Brand,
+			strconv.FormatFloat(product.Price, 'f', 2, 64),
+			strconv.FormatFloat(product.Rating, 'f', 1, 64),
+			strconv.FormatBool(product.InStock),
+		})
+	}
+	rows = append(rows, []string{fmt.Sprintf("(%d of %d hits)", len(result.Items), result.Total)})
+	return []string{"ID", "NAME", "BRAND", "PRICE", "RATING", "IN_STOCK"}, rows
+}
+
+// aggregationTable lists single value aggregations and the buckets of bucket aggregations
+func aggregationTable(aggs any) ([]string, [][]string) {
+	aggregations, _ := aggs.(map[string]interface{})
+	names := make([]string, 0, len(aggregations))
+	for name := range aggregations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var rows [][]string
+	for _, name := range names {
+		agg, _ := aggregations[name].(map[string]interface{})
+		if buckets, ok := agg["buckets"].([]interface{}); ok {
+			for _, b := range buckets {
+				bucket, _ := b.(map[string]interface{})
+				key := bucket["key_as_string"]
+				if key == nil {
+					key = bucket["key"]
+				}
+				rows = append(rows, []string{name, fmt.Sprint(key), fmt.Sprint(bucket["doc_count"]), ""})
+			}
+			continue
+		}
+		if value, ok := agg["value"]; ok {
+			rows = append(rows, []string{name, "", "", fmt.Sprint(value)})
+			continue
+		}
+		// Multi value metrics such as stats or percentiles
+		compact, _ := json.Marshal(agg)
+		rows = append(rows, []string{name, "", "", string(compact)})
+	}
+	return []string{"AGGREGATION", "KEY", "DOC_COUNT", "VALUE"}, rows
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"Elastic-Search/metrics"
+	"Elastic-Search/seed"
+)
+
+func runSeed(ctx context.Context, args []string) error {
+	var o options
+	fs := o.flagSet("seed", seed.ProductIndex, 10*time.Minute)
+	count := fs.Int("count", 10000, "Number of products to generate")
+	reloadSynonyms := fs.Bool("reload-synonyms", false, "Apply the synonyms file to the existing index instead of reseeding")
+	synonyms := fs.String("synonyms", "", "Synonyms file in Solr format, defaults to the built-in synonyms")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: Elastic-Search seed [flags]")
+		fs.PrintDefaults()
+	}
+	if err := o.parse(fs, args); err != nil {
+		return err
+	}
+	if *count < 1 {
+		return usagef("-count must be positive")
+	}
+
+	logger, err := o.logger(ctx)
+	if err != nil {
+		return err
+	}
+	client, err := o.esClient(ctx, newBreaker(logger), logger)
+	if err != nil {
+		return err
+	}
+
+	seeder := seed.NewSeeder(client, seed.Config{
+		Index:        o.index,
+		SynonymsFile: *synonyms,
+		Metrics:      metrics.Default,
+		Logger:       logger,
+	})
+
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+
+	if *reloadSynonyms {
+		if err := seeder.UpdateSynonyms(ctx); err != nil {
+			return err
+		}
+		return render(os.Stdout, o.output, map[string]interface{}{"index": o.index, "synonyms": "reloaded"},
+			func() ([]string, [][]string) {
+				return []string{"INDEX", "SYNONYMS"}, [][]string{{o.index, "reloaded"}}
+			})
+	}
+
+	if err := seeder.Seed(ctx, *count); err != nil {
+		return err
+	}
+	return render(os.Stdout, o.output, map[string]interface{}{"index": o.index, "products": *count},
+		func() ([]string, [][]string) {
+			return []string{"INDEX", "PRODUCTS"}, [][]string{{o.index, fmt.Sprint(*count)}}
+		})
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"Elastic-Search/crud"
+	"Elastic-Search/docstore"
+	"Elastic-Search/metrics"
+	"Elastic-Search/rpc"
+)
+
+var userActions = []struct{ name, summary string }{
+	{"create", "Create a user"},
+	{"get", "Get a user by id"},
+	{"update", "Change the name and/or email of a user"},
+	{"delete", "Delete a user by id"},
+	{"search", "Search users by name or email"},
+	{"serve", "Serve the users API over HTTP and/or gRPC"},
+}
+
+func userUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: Elastic-Search user <action> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Actions:")
+	for _, action := range userActions {
+		fmt.Fprintf(w, "  %-7s %s\n", action.name, action.summary)
+	}
+}
+
+func runUser(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		userUsage(os.Stderr)
+		if len(args) == 0 {
+			return usagef("user: missing action")
+		}
+		return nil
+	}
+	action := args[0]
+
+	var o options
+	fs := o.flagSet("user "+action, "users", 30*time.Second)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: Elastic-Search user %s [flags]\n", action)
+		fs.PrintDefaults()
+	}
+
+	var id, name, email, ifMatch, query, httpAddr, grpcAddr *string
+	var size *int
+	var upsert *bool
+	switch action {
+	case "create":
+		id = fs.String("id", "", "User id (required)")
+		name = fs.String("name", "", "Name (required)")
+		email = fs.String("email", "", "Email address (required)")
+	case "get", "delete":
+		id = fs.String("id", "", "User id (required)")
+	case "update":
+		id = fs.String("id", "", "User id (required)")
+		name = fs.String("name", "", "New name")
+		email = fs.String("email", "", "New email address")
+		ifMatch = fs.String("if-match", "", `Only update the user at this version, e.g. "12-1"`)
+		upsert = fs.Bool("upsert", false, "Create the user when it does not exist; needs -name and -email")
+	case "search":
+		query = fs.String("query", "", "Text to search for in names and emails (required)")
+		size = fs.Int("size", 10, "Maximum number of users")
+	case "serve":
+		httpAddr = fs.String("http", "", "Serve the REST users API on this address, e.g. :8081")
+		grpcAddr = fs.String("grpc", "", "Serve the gRPC user service on this address, e.g. :9091")
+	default:
+		userUsage(os.Stderr)
+		return usagef("user: unknown action %q", action)
+	}
+
+	if err := o.parse(fs, args[1:]); err != nil {
+		return err
+	}
+	switch {
+	case id != nil && *id == "":
+		return usagef("-id is required")
+	case action == "create" && (*name == "" || *email == ""):
+		return usagef("-name and -email are required")
+	case action == "update" && *upsert && (*name == "" || *email == ""):
+		return usagef("-upsert needs -name and -email")
+	case action == "update" && *upsert && *ifMatch != "":
+		return usagef("-upsert and -if-match cannot be combined")
+	case action == "search" && *query == "":
+		return usagef("-query is required")
+	case action == "search" && (*size < 1 || *size > 10000):
+		return usagef("-size must be between 1 and 10000")
+	case action == "serve" && *httpAddr == "" && *grpcAddr == "":
+		return usagef("at least one of -http and -grpc is required")
+	}
+
+	logger, err := o.logger(ctx)
+	if err != nil {
+		return err
+	}
+	userBreaker := newBreaker(logger)
+	client, err := o.esClient(ctx, userBreaker, logger)
+	if err != nil {
+		return err
+	}
+	config := crud.Config{
+		Client:    client,
+		Index:     o.index,
+		Breaker:   userBreaker,
+		Metrics:   metrics.Default,
+		Logger:    logger,
+		SlowQuery: o.config.SlowQuery,
+	}
+	ec, err := crud.NewElasticsearchClient(config)
+	if err != nil {
+		return err
+	}
+
+	if action == "serve" {
+		return serveUsers(ctx, ec, logger, *httpAddr, *grpcAddr)
+	}
+
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+
+	switch action {
+	case "create":
+		user := crud.User{ID: *id, Name: *name, Email: *email, CreatedAt: time.Now().UTC()}
+		if _, err := ec.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		return renderUsers(o.output, user)
+	case "get":
+		user, _, err := ec.GetUser(ctx, *id)
+		if err != nil {
+			return err
+		}
+		return renderUsers(o.output, *user)
+	case "update":
+		var patch crud.UserPatch
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "name":
+				patch.Name = name
+			case "email":
+				patch.Email = email
+			}
+		})
+		var version *crud.Version
+		if *ifMatch != "" {
+			parsed, err := crud.ParseETag(*ifMatch)
+			if err != nil {
+				return err
+			}
+			version = &parsed
+		}
+		options := docstore.UpdateOptions[crud.User]{IfMatch: version}
+		if *upsert {
+			options.Upsert = &crud.User{ID: *id, Name: *name, Email: *email, CreatedAt: time.Now().UTC()}
+		}
+		result, err := ec.UpdateUser(ctx, *id, patch, options)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "version: %s (%s)\n", result.Version.ETag(), result.Result)
+		return renderUsers(o.output, result.Source)
+	case "delete":
+		if err := ec.DeleteUser(ctx, *id, nil); err != nil {
+			return err
+		}
+		return render(os.Stdout, o.output, map[string]interface{}{"id": *id, "deleted": true},
+			func() ([]string, [][]string) {
+				return []string{"ID", "DELETED"}, [][]string{{*id, "true"}}
+			})
+	case "search":
+		users, err := ec.SearchUsers(ctx, *query, *size)
+		if err != nil {
+			return err
+		}
+		return renderUsers(o.output, users...)
+	}
+	return nil
+}
+
+// renderUsers prints one user as an object, or several as a list
+func renderUsers(format string, users ...crud.User) error {
+	var v interface{} = users
+	if len(users) == 1 {
+		v = users[0]
+	}
+	return render(os.Stdout, format, v, func() ([]string, [][]string) {
+		rows := make([][]string, 0, len(users))
+		for _, user := range users {
+			created := ""
+			if !user.CreatedAt.IsZero() {
+				created = user.CreatedAt.Format(time.RFC3339)
+			}
+			rows = append(rows, []string{user.ID, user.Name, user.Email, created})
+		}
+		return []string{"ID", "NAME", "EMAIL", "CREATED_AT"}, rows
+	})
+}
+
+// serveUsers serves the REST API and/or the gRPC service until ctx is cancelled
+func serveUsers(ctx context.Context, ec *crud.ElasticsearchClient, logger *slog.Logger, httpAddr, grpcAddr string) error {
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	var servers []func() error
+	if httpAddr != "" {
+		servers = append(servers, func() error {
+			return crud.NewUserServer(ec, logger).ListenAndServe(ctx, httpAddr)
+		})
+	}
+	if grpcAddr != "" {
+		servers = append(servers, func() error {
+			server := rpc.NewServer(logger)
+			rpc.RegisterUserServiceServer(server, crud.NewUserService(ec))
+			return rpc.ListenAndServe(ctx, server, grpcAddr, logger)
+		})
+	}
+	return rpc.ServeAll(stop, servers...)
+}
@@ -0,0 +1,225 @@
+// Command Elastic-Search seeds, searches and manages the products and users indices.
+//
+//	Elastic-Search seed --count 10000
+//	Elastic-Search search match --field name --query laptop
+//	Elastic-Search user get --id 3 --output table
+//	Elastic-Search repl --index products
+//
+// Run a command with -h to see its flags.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"Elastic-Search/breaker"
+	"Elastic-Search/crud"
+	"Elastic-Search/esconfig"
+	"Elastic-Search/eserrors"
+	"Elastic-Search/httpapi"
+	"Elastic-Search/logging"
+	"Elastic-Search/metrics"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// Exit codes of the CLI
+const (
+	exitOK          = 0
+	exitError       = 1 // Any failure not covered below
+	exitUsage       = 2 // Invalid command line or input
+	exitNotFound    = 3 // The document or index does not exist
+	exitConflict    = 4 // The document exists already or was changed concurrently
+	exitUnavailable = 5 // The cluster is unreachable, overloaded or too slow
+)
+
+// command is a subcommand of the CLI. run receives the arguments after the command name.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{name: "seed", summary: "Recreate the products index with generated products", run: runSeed},
+	{name: "search", summary: "Search the products index", run: runSearch},
+	{name: "user", summary: "Create, read, update, delete and search users", run: runUser},
+	{name: "apikey", summary: "Create, list, invalidate and rotate API keys", run: runAPIKey},
+	{name: "repl", summary: "Explore the products index interactively", run: runRepl},
+	{name: "config", summary: "Print the effective configuration, without secrets", run: runConfig},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage(os.Stderr)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(ctx, args[1:])
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+		return exitCode(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	usage(os.Stderr)
+	return exitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: Elastic-Search <command> [subcommand] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// usageError is an invalid command line or input
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// exitCode maps the error of a command to the exit code of the process
+func exitCode(err error) int {
+	var usage *usageError
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usage), errors.Is(err, httpapi.ErrInvalid), errors.Is(err, eserrors.ErrBadRequest):
+		return exitUsage
+	case errors.Is(err, eserrors.ErrNotFound), errors.Is(err, eserrors.ErrIndexNotFound):
+		return exitNotFound
+	case errors.Is(err, crud.ErrUserExists),
+		errors.Is(err, crud.ErrPreconditionFailed),
+		errors.Is(err, eserrors.ErrVersionConflict):
+		return exitConflict
+	case errors.Is(err, breaker.ErrOpen),
+		errors.Is(err, breaker.ErrTooManyInFlight),
+		errors.Is(err, eserrors.ErrUnavailable),
+		errors.Is(err, eserrors.ErrTooManyRequests),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr):
+		return exitUnavailable
+	}
+	return exitError
+}
+
+// options are the flags shared by every command. config is set by parse.
+type options struct {
+	flags   *esconfig.Flags
+	config  *esconfig.Config
+	index   string
+	output  string
+	timeout time.Duration
+}
+
+// flagSet creates the flag set of a subcommand with the shared flags registered
+func (o *options) flagSet(name, defaultIndex string, defaultTimeout time.Duration) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	o.flags = esconfig.RegisterFlags(fs)
+	fs.StringVar(&o.index, "index", defaultIndex, "Index to use")
+	fs.StringVar(&o.output, "output", "json", "Output format: json or table")
+	fs.DurationVar(&o.timeout, "timeout", defaultTimeout, "Time limit of the command; 0 disables it. Servers ignore it")
+	return fs
+}
+
+// parse parses the flags of a subcommand, rejects positional arguments and loads the configuration
+func (o *options) parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{message: err.Error()}
+	}
+	if fs.NArg() > 0 {
+		return usagef("%s: unexpected arguments %q", fs.Name(), fs.Args())
+	}
+	if o.output != "json" && o.output != "table" {
+		return usagef("-output must be json or table")
+	}
+	config, err := o.flags.Load()
+	if err != nil {
+		return &usageError{message: "invalid configuration: " + err.Error()}
+	}
+	o.config = config
+	return nil
+}
+
+// logger creates the logger of the command and starts the metrics server if requested.
+// The metrics server stops with ctx.
+func (o *options) logger(ctx context.Context) (*slog.Logger, error) {
+	level, err := o.config.Level()
+	if err != nil {
+		return nil, &usageError{message: err.Error()}
+	}
+	logger := logging.New(os.Stderr, level, false)
+	if o.config.TLS.Insecure {
+		logger.Warn("TLS certificate verification is disabled")
+	}
+	if o.config.MetricsAddr != "" {
+		if err := metrics.Serve(ctx, o.config.MetricsAddr, logger); err != nil {
+			return nil, err
+		}
+	}
+	return logger, nil
+}
+
+// withTimeout bounds ctx by the -timeout flag
+func (o *options) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, o.timeout)
+}
+
+// esClient creates an Elasticsearch client whose transport is guarded by b.
+// It waits for the cluster as set by -wait-ready and -wait-for-status.
+func (o *options) esClient(ctx context.Context, b *breaker.Breaker, logger *slog.Logger) (*elasticsearch.Client, error) {
+	return esconfig.NewClient(ctx, o.config, esconfig.ClientOptions{Breaker: b, Logger: logger})
+}
+
+// newBreaker creates a circuit breaker that logs its state changes
+func newBreaker(logger *slog.Logger) *breaker.Breaker {
+	b := breaker.New(breaker.DefaultConfig())
+	b.OnStateChange(func(from, to breaker.State) {
+		logger.Warn("circuit breaker state changed", "from", from.String(), "to", to.String())
+	})
+	return b
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"Elastic-Search/breaker"
+	"Elastic-Search/crud"
+	"Elastic-Search/eserrors"
+	"Elastic-Search/httpapi"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, exitOK},
+		{"usage", usagef("--id is required"), exitUsage},
+		{"invalid user", fmt.Errorf("error creating user: %w", httpapi.Invalid("name is required")), exitUsage},
+		{"bad request", eserrors.Parse("search", 400, []byte(`{"error": {"type": "parsing_exception"}, "status": 400}`)), exitUsage},
+		{"document not found", eserrors.Parse("get user", 404, []byte(`{"found": false}`)), exitNotFound},
+		{"index not found", eserrors.Parse("search", 404, []byte(`{"error": {"type": "index_not_found_exception"}, "status": 404}`)), exitNotFound},
+		{"user exists", fmt.Errorf("%w: %w", crud.ErrUserExists, eserrors.ErrVersionConflict), exitConflict},
+		{"precondition failed", crud.ErrPreconditionFailed, exitConflict},
+		{"version conflict", eserrors.Parse("update user", 409, []byte(`{"error": {"type": "version_conflict_engine_exception"}, "status": 409}`)), exitConflict},
+		{"breaker open", fmt.Errorf("error searching: %w", breaker.ErrOpen), exitUnavailable},
+		{"too many in flight", breaker.ErrTooManyInFlight, exitUnavailable},
+		{"cluster unavailable", eserrors.Parse("search", 503, nil), exitUnavailable},
+		{"too many requests", eserrors.Parse("search", 429, nil), exitUnavailable},
+		{"timeout", fmt.Errorf("error searching: %w", context.DeadlineExceeded), exitUnavailable},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, exitUnavailable},
+		{"unauthorized", eserrors.Parse("search", 401, nil), exitError},
+		{"other", errors.New("boom"), exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	type row struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	rows := []row{{ID: "1", Name: "John"}, {ID: "10", Name: "Jane Doe"}}
+	table := func() ([]string, [][]string) {
+		cells := make([][]string, 0, len(rows))
+		for _, r := range rows {
+			cells = append(cells, []string{r.ID, r.Name})
+		}
+		return []string{"ID", "NAME"}, cells
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", "[\n  {\n    \"id\": \"1\",\n    \"name\": \"John\"\n  },\n  {\n    \"id\": \"10\",\n    \"name\": \"Jane Doe\"\n  }\n]\n"},
+		{"", "[\n  {\n    \"id\": \"1\",\n    \"name\": \"John\"\n  },\n  {\n    \"id\": \"10\",\n    \"name\": \"Jane Doe\"\n  }\n]\n"},
+		{"table", "ID  NAME\n1   John\n10  Jane Doe\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := render(&buf, tt.format, rows, table); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("render(%q) = %q, want %q", tt.format, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// printJSON writes v as indented JSON
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printTable writes rows as columns aligned under header
+func printTable(w io.Writer, header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// render writes v as JSON, or as the table built by table when format is "table"
+func render(w io.Writer, format string, v interface{}, table func() ([]string, [][]string)) error {
+	if format == "table" {
+		header, rows := table()
+		return printTable(w, header, rows)
+	}
+	return printJSON(w, v)
+}
+
+// treeNode is a line of a tree and the lines nested under it
+type treeNode struct {
+	label    string
+	children []treeNode
+}
+
+// printTree writes nodes with box drawing branches, children indented under their parent
+func printTree(w io.Writer, nodes []treeNode, prefix string) {
+	for i, node := range nodes {
+		branch, indent := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s\n", prefix, branch, node.label)
+		printTree(w, node.children, prefix+indent)
+	}
+}
@@ -0,0 +1,226 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"Elastic-Search/breaker"
+	"Elastic-Search/docstore"
+	"Elastic-Search/esconfig"
+	"Elastic-Search/eserrors"
+	"Elastic-Search/httpapi"
+	"Elastic-Search/metrics"
+	"Elastic-Search/retry"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// User represents a user document in Elasticsearch
+type User struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Version identifies a revision of a user
+type Version = docstore.Version
+
+// Errors returned in place of the version conflicts they stand for; the conflict stays in the chain
+var (
+	ErrUserExists         = errors.New("a user with this id already exists")
+	ErrPreconditionFailed = errors.New("user was modified, fetch it again and retry")
+)
+
+// UserPatch holds the fields of a user to change; nil fields are left as they are
+type UserPatch struct {
+	Name  *string `json:"name,omitempty"`
+	Email *string `json:"email,omitempty"`
+}
+
+// conflictRetries is how often an unconditional update merges again after a concurrent change
+const conflictRetries = 3
+
+// Config holds Elasticsearch configuration
+type Config struct {
+	Client    *elasticsearch.Client // Optional: Shared client from esconfig.NewClient; the connection settings below are then ignored
+	Addresses []string
+	CloudID   string // Optional: Elastic Cloud ID, used instead of Addresses
+	Username  string
+	Password  string
+	APIKey    string
+	Index     string
+	CACert    string           // Optional: Path to a PEM CA bundle, overrides TLS.CAFile
+	TLS       esconfig.TLS     // Optional: CA bundle, client certificate, fingerprint pinning or insecure mode
+	Retry     *retry.Policies  // Optional: Retry policies per operation, defaults to retry.DefaultPolicies()
+	Breaker   *breaker.Breaker // Optional: Circuit breaker wrapping the transport, may be shared between clients; with Client it is only reported
+	Metrics   *metrics.Metrics // Optional: Prometheus metrics for every operation
+	Logger    *slog.Logger     // Optional: Structured logger, defaults to slog.Default()
+	SlowQuery time.Duration    // Optional: Log request bodies of operations slower than this
+}
+
+// ElasticsearchClient stores users in a docstore.Repository and adds the rules specific to them
+type ElasticsearchClient struct {
+	users *docstore.Repository[User]
+}
+
+// Users returns the repository of the users, e.g. for Exists or MultiGet
+func (c *ElasticsearchClient) Users() *docstore.Repository[User] {
+	return c.users
+}
+
+// BreakerState returns the state of the circuit breaker guarding the client's transport
+func (c *ElasticsearchClient) BreakerState() breaker.State {
+	return c.users.BreakerState()
+}
+
+// NewElasticsearchClient creates a new Elasticsearch client. Unless config.Client is set it
+// connects through esconfig.NewClient and fails when the cluster does not answer.
+func NewElasticsearchClient(config Config) (*ElasticsearchClient, error) {
+	client := config.Client
+	if client == nil {
+		connection := esconfig.Default()
+		if len(config.Addresses) > 0 || config.CloudID != "" {
+			connection.Addresses = config.Addresses
+		}
+		connection.CloudID = config.CloudID
+		connection.Username = config.Username
+		connection.Password = esconfig.Secret(config.Password)
+		connection.APIKey = esconfig.Secret(config.APIKey)
+		connection.TLS = config.TLS
+		if config.CACert != "" {
+			connection.TLS.CAFile = config.CACert
+		}
+
+		var err error
+		client, err = esconfig.NewClient(context.Background(), connection, esconfig.ClientOptions{
+			Breaker: config.Breaker,
+			Logger:  config.Logger,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	index := config.Index
+	if index == "" {
+		index = "users"
+	}
+	users, err := docstore.New(client, docstore.Config[User]{
+		Index:     index,
+		ID:        func(user User) string { return user.ID },
+		Name:      "user",
+		Component: "crud",
+		Retry:     config.Retry,
+		Breaker:   config.Breaker,
+		Metrics:   config.Metrics,
+		Logger:    config.Logger,
+		SlowQuery: config.SlowQuery,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ElasticsearchClient{users: users}, nil
+}
+
+// CreateUser indexes a new user and fails with ErrUserExists if the id is taken
+func (c *ElasticsearchClient) CreateUser(ctx context.Context, user User) (Version, error) {
+	version, err := c.users.Create(ctx, user)
+	if errors.Is(err, eserrors.ErrVersionConflict) {
+		return Version{}, fmt.Errorf("%w: %w", ErrUserExists, err)
+	}
+	return version, err
+}
+
+// PatchUser changes the fields set in patch and returns the user with its new version.
+// When ifMatch is set the user is only changed while it is still at that version.
+func (c *ElasticsearchClient) PatchUser(
+	ctx context.Context,
+	userId string,
+	patch UserPatch,
+	ifMatch *Version,
+) (*User, Version, error) {
+	result, err := c.UpdateUser(ctx, userId, patch, docstore.UpdateOptions[User]{IfMatch: ifMatch})
+	if err != nil {
+		return nil, Version{}, err
+	}
+	return &result.Source, result.Version, nil
+}
+
+// UpdateUser changes only the fields set in patch and returns the user as stored.
+// Without options.IfMatch, concurrent changes to other fields are merged instead of failing.
+// With options.Upsert the user is created from it when missing; it must then have userId.
+// With options.DocAsUpsert the user is created from the patch and userId when missing, so
+// the patch must set both name and email; such a user has no creation time.
+// A patch that changes nothing leaves the version as it is and reports a noop result.
+func (c *ElasticsearchClient) UpdateUser(
+	ctx context.Context,
+	userId string,
+	patch UserPatch,
+	options docstore.UpdateOptions[User],
+) (*docstore.UpdateResult[User], error) {
+	if err := validatePatch(patch); err != nil {
+		return nil, err
+	}
+	var partial interface{} = patch
+	if options.DocAsUpsert {
+		if patch.Name == nil || patch.Email == nil {
+			return nil, httpapi.Invalid("a patch upserted as a user must set name and email")
+		}
+		// The patch becomes the user when it is missing, so it carries the id as well
+		partial = struct {
+			ID string `json:"id"`
+			UserPatch
+		}{ID: userId, UserPatch: patch}
+	}
+	if options.Upsert != nil {
+		if options.Upsert.ID != userId {
+			return nil, httpapi.Invalid("upsert id %q does not match %q", options.Upsert.ID, userId)
+		}
+		if err := validateUser(*options.Upsert); err != nil {
+			return nil, err
+		}
+	}
+	if options.IfMatch == nil && options.RetryOnConflict == 0 {
+		options.RetryOnConflict = conflictRetries
+	}
+
+	result, err := c.users.Update(ctx, userId, partial, options)
+	if err != nil {
+		if options.IfMatch != nil && errors.Is(err, eserrors.ErrVersionConflict) {
+			return nil, fmt.Errorf("%w: %w", ErrPreconditionFailed, err)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteUser deletes a user. When ifMatch is set the delete only succeeds at that version.
+func (c *ElasticsearchClient) DeleteUser(ctx context.Context, userId string, ifMatch *Version) error {
+	err := c.users.Delete(ctx, userId, ifMatch)
+	if ifMatch != nil && errors.Is(err, eserrors.ErrVersionConflict) {
+		return fmt.Errorf("%w: %w", ErrPreconditionFailed, err)
+	}
+	return err
+}
+
+// GetUser returns a user along with its current version
+func (c *ElasticsearchClient) GetUser(ctx context.Context, userId string) (*User, Version, error) {
+	return c.users.Get(ctx, userId)
+}
+
+// SearchUsers returns up to size users whose name or email matches query
+func (c *ElasticsearchClient) SearchUsers(ctx context.Context, query string, size int) ([]User, error) {
+	return c.users.Search(ctx, map[string]interface{}{
+		"query": map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":  query,
+				"fields": []string{"name", "email"},
+			},
+		},
+		"size": size,
+	})
+}
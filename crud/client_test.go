@@ -0,0 +1,180 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"Elastic-Search/docstore"
+	"Elastic-Search/httpapi"
+	"Elastic-Search/internal/estest"
+)
+
+// recordingTransport answers every request with body and keeps the request bodies and URLs
+type recordingTransport struct {
+	body     string
+	requests []map[string]interface{}
+	urls     []*url.URL
+}
+
+func (f *recordingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	var request map[string]interface{}
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&request)
+	}
+	f.requests = append(f.requests, request)
+	f.urls = append(f.urls, r.URL)
+
+	return estest.Response(r, http.StatusOK, f.body), nil
+}
+
+func newTestClient(t *testing.T, transport *recordingTransport) *ElasticsearchClient {
+	t.Helper()
+	client := estest.NewClient(t, transport)
+	ec, err := NewElasticsearchClient(Config{Client: client, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ec
+}
+
+func TestUpdateUser(t *testing.T) {
+	transport := &recordingTransport{body: `{
+		"_id": "1", "_seq_no": 4, "_primary_term": 1, "result": "updated",
+		"get": {"_source": {"id": "1", "name": "Johnny", "email": "john@example.com"}}
+	}`}
+	ec := newTestClient(t, transport)
+	name := "Johnny"
+
+	result, err := ec.UpdateUser(context.Background(), "1", UserPatch{Name: &name}, docstore.UpdateOptions[User]{})
+	if err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if result.Noop() || result.Source.Email != "john@example.com" || result.Version != (Version{SeqNo: 4, PrimaryTerm: 1}) {
+		t.Errorf("UpdateUser() = %+v, want the stored user at 4-1", result)
+	}
+
+	want := map[string]interface{}{"doc": map[string]interface{}{"name": "Johnny"}}
+	if len(transport.requests) != 1 || !reflect.DeepEqual(transport.requests[0], want) {
+		t.Fatalf("sent %v, want %v", transport.requests, want)
+	}
+	query := transport.urls[0].Query()
+	if transport.urls[0].Path != "/users/_update/1" || query.Get("_source") != "true" || query.Get("retry_on_conflict") != "3" {
+		t.Errorf("sent to %s, want /users/_update/1 with the source and conflict retries", transport.urls[0])
+	}
+}
+
+func TestUpdateUserIfMatch(t *testing.T) {
+	transport := &recordingTransport{body: `{"_id": "1", "_seq_no": 5, "_primary_term": 1, "result": "updated"}`}
+	ec := newTestClient(t, transport)
+	email := "johnny@example.com"
+
+	_, err := ec.UpdateUser(context.Background(), "1", UserPatch{Email: &email},
+		docstore.UpdateOptions[User]{IfMatch: &Version{SeqNo: 4, PrimaryTerm: 1}})
+	if err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	query := transport.urls[0].Query()
+	if query.Get("if_seq_no") != "4" || query.Get("if_primary_term") != "1" || query.Has("retry_on_conflict") {
+		t.Errorf("sent to %s, want if_seq_no=4 and if_primary_term=1 without conflict retries", transport.urls[0])
+	}
+}
+
+func TestUpdateUserNoop(t *testing.T) {
+	transport := &recordingTransport{body: `{
+		"_id": "1", "_seq_no": 4, "_primary_term": 1, "result": "noop",
+		"get": {"_source": {"id": "1", "name": "John", "email": "john@example.com"}}
+	}`}
+	ec := newTestClient(t, transport)
+	detectNoop := false
+	name := "John"
+
+	result, err := ec.UpdateUser(context.Background(), "1", UserPatch{Name: &name},
+		docstore.UpdateOptions[User]{DetectNoop: &detectNoop})
+	if err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if !result.Noop() {
+		t.Errorf("UpdateUser() result = %s, want noop", result.Result)
+	}
+	if got := transport.requests[0]["detect_noop"]; got != false {
+		t.Errorf("detect_noop = %v, want false", got)
+	}
+}
+
+func TestUpdateUserInvalid(t *testing.T) {
+	name, empty := "John", ""
+	tests := []struct {
+		name    string
+		patch   UserPatch
+		options docstore.UpdateOptions[User]
+	}{
+		{"empty patch", UserPatch{}, docstore.UpdateOptions[User]{}},
+		{"empty name", UserPatch{Name: &empty}, docstore.UpdateOptions[User]{}},
+		{"upsert with another id", UserPatch{Name: &name},
+			docstore.UpdateOptions[User]{Upsert: &User{ID: "2", Name: "John", Email: "john@example.com"}}},
+		{"invalid upsert", UserPatch{Name: &name},
+			docstore.UpdateOptions[User]{Upsert: &User{ID: "1", Name: "John"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &recordingTransport{}
+			ec := newTestClient(t, transport)
+
+			_, err := ec.UpdateUser(context.Background(), "1", tt.patch, tt.options)
+			if !errors.Is(err, httpapi.ErrInvalid) {
+				t.Fatalf("UpdateUser() error = %v, want %v", err, httpapi.ErrInvalid)
+			}
+			if len(transport.requests) != 0 {
+				t.Errorf("invalid update reached Elasticsearch")
+			}
+		})
+	}
+}
+
+func TestUpdateUserDocAsUpsert(t *testing.T) {
+	transport := &recordingTransport{body: `{
+		"_id": "1", "_seq_no": 0, "_primary_term": 1, "result": "created",
+		"get": {"_source": {"id": "1", "name": "John", "email": "john@example.com"}}
+	}`}
+	ec := newTestClient(t, transport)
+	name, email := "John", "john@example.com"
+
+	result, err := ec.UpdateUser(context.Background(), "1", UserPatch{Name: &name, Email: &email},
+		docstore.UpdateOptions[User]{DocAsUpsert: true})
+	if err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if result.Result != "created" || result.Source.ID != "1" {
+		t.Errorf("UpdateUser() = %+v, want the created user", result)
+	}
+
+	want := map[string]interface{}{
+		"doc":           map[string]interface{}{"id": "1", "name": "John", "email": "john@example.com"},
+		"doc_as_upsert": true,
+	}
+	if len(transport.requests) != 1 || !reflect.DeepEqual(transport.requests[0], want) {
+		t.Errorf("sent %v, want %v", transport.requests, want)
+	}
+}
+
+func TestUpdateUserDocAsUpsertIncompletePatch(t *testing.T) {
+	transport := &recordingTransport{}
+	ec := newTestClient(t, transport)
+	name := "John"
+
+	_, err := ec.UpdateUser(context.Background(), "1", UserPatch{Name: &name},
+		docstore.UpdateOptions[User]{DocAsUpsert: true})
+	if !errors.Is(err, httpapi.ErrInvalid) {
+		t.Fatalf("UpdateUser() error = %v, want %v", err, httpapi.ErrInvalid)
+	}
+	if len(transport.requests) != 0 {
+		t.Errorf("invalid upsert reached Elasticsearch")
+	}
+}
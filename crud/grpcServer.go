@@ -0,0 +1,165 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"Elastic-Search/httpapi"
+	"Elastic-Search/rpc"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// userService implements rpc.UserServiceServer on top of an ElasticsearchClient
+type userService struct {
+	rpc.UnimplementedUserServiceServer
+	ec *ElasticsearchClient
+}
+
+// NewUserService returns the gRPC user service backed by ec
+func NewUserService(ec *ElasticsearchClient) rpc.UserServiceServer {
+	return &userService{ec: ec}
+}
+
+func (s *userService) CreateUser(ctx context.Context, req *rpc.CreateUserRequest) (*rpc.UserResponse, error) {
+	user := fromProtoUser(req.GetUser())
+	if err := validateID(user.ID); err != nil {
+		return nil, grpcStatus(err)
+	}
+	if err := validateUser(user); err != nil {
+		return nil, grpcStatus(err)
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now().UTC()
+	}
+
+	version, err := s.ec.CreateUser(ctx, user)
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	return userResponse(&user, version), nil
+}
+
+func (s *userService) GetUser(ctx context.Context, req *rpc.GetUserRequest) (*rpc.UserResponse, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, grpcStatus(err)
+	}
+	user, version, err := s.ec.GetUser(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	return userResponse(user, version), nil
+}
+
+func (s *userService) UpdateUser(ctx context.Context, req *rpc.UpdateUserRequest) (*rpc.UserResponse, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, grpcStatus(err)
+	}
+
+	var ifMatch *Version
+	if req.GetIfMatch() != nil {
+		version := fromProtoVersion(req.GetIfMatch())
+		ifMatch = &version
+	}
+	user, version, err := s.ec.PatchUser(ctx, req.GetId(), UserPatch{Name: req.Name, Email: req.Email}, ifMatch)
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	return userResponse(user, version), nil
+}
+
+func (s *userService) DeleteUser(ctx context.Context, req *rpc.DeleteUserRequest) (*rpc.DeleteUserResponse, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, grpcStatus(err)
+	}
+
+	var version *Version
+	if req.GetIfMatch() != nil {
+		v := fromProtoVersion(req.GetIfMatch())
+		version = &v
+	}
+	if err := s.ec.DeleteUser(ctx, req.GetId(), version); err != nil {
+		return nil, grpcStatus(err)
+	}
+	return &rpc.DeleteUserResponse{}, nil
+}
+
+func (s *userService) SearchUsers(req *rpc.SearchUsersRequest, stream rpc.UserService_SearchUsersServer) error {
+	size := int(req.GetSize())
+	if size == 0 {
+		size = defaultSearchSize
+	}
+	switch {
+	case strings.TrimSpace(req.GetQuery()) == "":
+		return grpcStatus(httpapi.Invalid("query is required"))
+	case size < 0 || size > maxSearchSize:
+		return grpcStatus(httpapi.Invalid("size must be between 1 and %d", maxSearchSize))
+	}
+
+	users, err := s.ec.SearchUsers(stream.Context(), req.GetQuery(), size)
+	if err != nil {
+		return grpcStatus(err)
+	}
+	for i := range users {
+		if err := stream.Send(toProtoUser(&users[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fromProtoUser(user *rpc.User) User {
+	u := User{
+		ID:    user.GetId(),
+		Name:  user.GetName(),
+		Email: user.GetEmail(),
+	}
+	if user.GetCreatedAt() != nil {
+		u.CreatedAt = user.GetCreatedAt().AsTime()
+	}
+	return u
+}
+
+func toProtoUser(user *User) *rpc.User {
+	u := &rpc.User{
+		Id:    user.ID,
+		Name:  user.Name,
+		Email: user.Email,
+	}
+	if !user.CreatedAt.IsZero() {
+		u.CreatedAt = timestamppb.New(user.CreatedAt)
+	}
+	return u
+}
+
+func fromProtoVersion(version *rpc.Version) Version {
+	return Version{SeqNo: int(version.GetSeqNo()), PrimaryTerm: int(version.GetPrimaryTerm())}
+}
+
+func userResponse(user *User, version Version) *rpc.UserResponse {
+	return &rpc.UserResponse{
+		User: toProtoUser(user),
+		Version: &rpc.Version{
+			SeqNo:       int64(version.SeqNo),
+			PrimaryTerm: int64(version.PrimaryTerm),
+		},
+	}
+}
+
+// grpcStatus maps validation and precondition errors, then defers to rpc.Status
+func grpcStatus(err error) error {
+	var validation *httpapi.ValidationError
+	switch {
+	case errors.As(err, &validation):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrUserExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ErrPreconditionFailed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return rpc.Status(err)
+}
@@ -0,0 +1,318 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"Elastic-Search/eserrors"
+	"Elastic-Search/httpapi"
+)
+
+// Limits of the REST API
+const (
+	defaultSearchSize = 10
+	maxSearchSize     = 100
+	maxIDLength       = 512 // Elasticsearch rejects longer document ids
+	maxBodyBytes      = 64 << 10
+)
+
+// UserServer exposes the user CRUD operations as a REST resource
+type UserServer struct {
+	ec  *ElasticsearchClient
+	api *httpapi.API
+}
+
+// NewUserServer creates a server in front of ec
+func NewUserServer(ec *ElasticsearchClient, logger *slog.Logger) *UserServer {
+	return &UserServer{ec: ec, api: &httpapi.API{
+		Name:                  "users API",
+		Logger:                logger,
+		Status:                errorStatus,
+		NotFoundMessage:       "user not found",
+		MaxBodyBytes:          maxBodyBytes,
+		DisallowUnknownFields: true,
+	}}
+}
+
+// Handler returns the routes of the users API:
+//
+//	POST   /users          create a user, 409 if the id is taken
+//	GET    /users?q=john   search users by name or email
+//	GET    /users/{id}     get a user
+//	PATCH  /users/{id}     change the name and/or email of a user
+//	DELETE /users/{id}     delete a user
+//
+// Responses for a single user carry an ETag built from the document's seq_no and
+// primary_term. PATCH and DELETE honor If-Match and answer 412 when the user changed.
+func (s *UserServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /users", s.handleCreate)
+	mux.HandleFunc("GET /users", s.handleSearch)
+	mux.HandleFunc("GET /users/{id}", s.handleGet)
+	mux.HandleFunc("PATCH /users/{id}", s.handlePatch)
+	mux.HandleFunc("DELETE /users/{id}", s.handleDelete)
+	mux.HandleFunc("GET /healthz", httpapi.Health(s.ec.BreakerState))
+	return mux
+}
+
+// ListenAndServe serves the users API on addr until ctx is cancelled
+func (s *UserServer) ListenAndServe(ctx context.Context, addr string) error {
+	return s.api.ListenAndServe(ctx, addr, s.Handler())
+}
+
+func (s *UserServer) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var user User
+	err := s.api.Decode(w, r, &user)
+	if err == nil {
+		err = validateID(user.ID)
+	}
+	if err == nil {
+		err = validateUser(user)
+	}
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now().UTC()
+	}
+
+	version, err := s.ec.CreateUser(r.Context(), user)
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/users/"+url.PathEscape(user.ID))
+	w.Header().Set("ETag", version.ETag())
+	httpapi.WriteJSON(w, http.StatusCreated, user)
+}
+
+func (s *UserServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := validateID(id); err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+
+	user, version, err := s.ec.GetUser(r.Context(), id)
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", version.ETag())
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && matchesETag(ifNoneMatch, version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	httpapi.WriteJSON(w, http.StatusOK, user)
+}
+
+func (s *UserServer) handlePatch(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var patch UserPatch
+	err := validateID(id)
+	if err == nil {
+		err = s.api.Decode(w, r, &patch)
+	}
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+
+	ifMatch, err := s.ifMatch(r, id)
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+
+	user, version, err := s.ec.PatchUser(r.Context(), id, patch, ifMatch)
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", version.ETag())
+	httpapi.WriteJSON(w, http.StatusOK, user)
+}
+
+func (s *UserServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := validateID(id); err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+
+	ifMatch, err := s.ifMatch(r, id)
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+
+	if err := s.ec.DeleteUser(r.Context(), id, ifMatch); err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *UserServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	size := defaultSearchSize
+	var err error
+	if strings.TrimSpace(q.Get("q")) == "" {
+		err = httpapi.Invalid("q is required")
+	}
+	if err == nil && q.Get("size") != "" {
+		if size, err = strconv.Atoi(q.Get("size")); err != nil || size < 1 || size > maxSearchSize {
+			err = httpapi.Invalid("size must be an integer between 1 and %d", maxSearchSize)
+		}
+	}
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+
+	users, err := s.ec.SearchUsers(r.Context(), q.Get("q"), size)
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+	httpapi.WriteJSON(w, http.StatusOK, map[string]interface{}{"users": users})
+}
+
+func validateID(id string) error {
+	switch {
+	case strings.TrimSpace(id) == "":
+		return httpapi.Invalid("id is required")
+	case len(id) > maxIDLength:
+		return httpapi.Invalid("id must not exceed %d bytes", maxIDLength)
+	case strings.HasPrefix(id, "_"):
+		return httpapi.Invalid("id must not start with an underscore")
+	}
+	return nil
+}
+
+func validateUser(user User) error {
+	if err := validateName(user.Name); err != nil {
+		return err
+	}
+	return validateEmail(user.Email)
+}
+
+// validatePatch checks the fields a patch sets like validateUser checks a whole user
+func validatePatch(patch UserPatch) error {
+	if patch.Name == nil && patch.Email == nil {
+		return httpapi.Invalid("patch must change name or email")
+	}
+	if patch.Name != nil {
+		if err := validateName(*patch.Name); err != nil {
+			return err
+		}
+	}
+	if patch.Email != nil {
+		return validateEmail(*patch.Email)
+	}
+	return nil
+}
+
+func validateName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return httpapi.Invalid("name is required")
+	}
+	return nil
+}
+
+func validateEmail(email string) error {
+	if email == "" {
+		return httpapi.Invalid("email is required")
+	}
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		return httpapi.Invalid("email %q is not a valid address", email)
+	}
+	return nil
+}
+
+// ParseETag parses an entity tag created by Version.ETag. The quotes are optional.
+func ParseETag(tag string) (Version, error) {
+	tag = strings.TrimSpace(tag)
+	value := strings.TrimSuffix(strings.TrimPrefix(tag, `"`), `"`)
+	seqNo, primaryTerm, found := strings.Cut(value, "-")
+	if !found {
+		return Version{}, httpapi.Invalid("malformed entity tag %s", tag)
+	}
+
+	var version Version
+	var err error
+	if version.SeqNo, err = strconv.Atoi(seqNo); err != nil || version.SeqNo < 0 {
+		return Version{}, httpapi.Invalid("malformed entity tag %s", tag)
+	}
+	if version.PrimaryTerm, err = strconv.Atoi(primaryTerm); err != nil || version.PrimaryTerm < 1 {
+		return Version{}, httpapi.Invalid("malformed entity tag %s", tag)
+	}
+	return version, nil
+}
+
+// ifMatch returns the version the If-Match header of r allows a write of the user at,
+// or nil when the header is missing or "*". A single tag is passed on to Elasticsearch as
+// is. A list is compared with the current version of the user, which the write is then
+// conditioned on. If-Match compares strongly, so weak tags never match.
+func (s *UserServer) ifMatch(r *http.Request, id string) (*Version, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch {
+	case header == "" || header == "*":
+		return nil, nil
+	case strings.Contains(header, ","):
+		_, version, err := s.ec.GetUser(r.Context(), id)
+		if err != nil {
+			return nil, err
+		}
+		if !matchesETag(header, version) {
+			return nil, ErrPreconditionFailed
+		}
+		return &version, nil
+	case strings.HasPrefix(header, "W/"):
+		return nil, ErrPreconditionFailed
+	}
+	version, err := ParseETag(header)
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// matchesETag reports whether an If-Match or If-None-Match header names version.
+// The header may list several tags; "*" matches any version. Unparseable tags never match.
+func matchesETag(header string, version Version) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if parsed, err := ParseETag(tag); err == nil && parsed == version {
+			return true
+		}
+	}
+	return false
+}
+
+// errorStatus maps the errors specific to users to HTTP statuses
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrPreconditionFailed):
+		return http.StatusPreconditionFailed, "precondition_failed"
+	case errors.Is(err, eserrors.ErrNotFound):
+		// Includes a missing index: it is created with the first user
+		return http.StatusNotFound, "not_found"
+	case errors.Is(err, ErrUserExists), errors.Is(err, eserrors.ErrVersionConflict):
+		return http.StatusConflict, "conflict"
+	case errors.Is(err, eserrors.ErrMappingConflict), errors.Is(err, eserrors.ErrBadRequest):
+		return http.StatusBadRequest, "invalid_document"
+	}
+	return 0, ""
+}
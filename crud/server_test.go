@@ -0,0 +1,449 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeUsersCluster is an in-memory users index that speaks enough of the document API
+// for the users server: create, get, index, update, delete and search with seq_no
+// and primary_term. The primary term is always 1.
+type fakeUsersCluster struct {
+	mu    sync.Mutex
+	docs  map[string]fakeDocument
+	seqNo int
+}
+
+type fakeDocument struct {
+	source map[string]interface{}
+	seqNo  int
+}
+
+func (f *fakeUsersCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.docs == nil {
+		f.docs = map[string]fakeDocument{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/":
+		f.write(w, http.StatusOK, map[string]interface{}{"version": map[string]string{"number": "8.15.0"}})
+	case len(parts) == 2 && parts[1] == "_search":
+		f.search(w)
+	case len(parts) == 3 && parts[1] == "_create":
+		f.create(w, parts[2], body)
+	case len(parts) == 3 && parts[1] == "_update":
+		f.update(w, r, parts[2], body)
+	case len(parts) == 3 && parts[1] == "_doc":
+		id := parts[2]
+		switch {
+		case r.Method == http.MethodGet || r.Method == http.MethodHead:
+			f.get(w, id)
+		case r.Method == http.MethodDelete:
+			f.remove(w, r, id)
+		case r.URL.Query().Get("op_type") == "create":
+			f.create(w, id, body)
+		default:
+			f.index(w, r, id, body)
+		}
+	default:
+		f.fail(w, http.StatusBadRequest, "illegal_argument_exception", "unexpected request "+r.Method+" "+r.URL.Path)
+	}
+}
+
+func (f *fakeUsersCluster) create(w http.ResponseWriter, id string, source map[string]interface{}) {
+	if _, ok := f.docs[id]; ok {
+		f.fail(w, http.StatusConflict, "version_conflict_engine_exception", "document already exists")
+		return
+	}
+	f.store(w, http.StatusCreated, "created", id, source)
+}
+
+func (f *fakeUsersCluster) index(w http.ResponseWriter, r *http.Request, id string, source map[string]interface{}) {
+	if !f.matches(r, id) {
+		f.fail(w, http.StatusConflict, "version_conflict_engine_exception", "version conflict")
+		return
+	}
+	f.store(w, http.StatusOK, "updated", id, source)
+}
+
+func (f *fakeUsersCluster) update(w http.ResponseWriter, r *http.Request, id string, body map[string]interface{}) {
+	partial, _ := body["doc"].(map[string]interface{})
+	doc, ok := f.docs[id]
+	if !ok {
+		switch upsert, _ := body["upsert"].(map[string]interface{}); {
+		case upsert != nil:
+			f.store(w, http.StatusCreated, "created", id, upsert)
+		case body["doc_as_upsert"] == true:
+			f.store(w, http.StatusCreated, "created", id, partial)
+		default:
+			f.fail(w, http.StatusNotFound, "document_missing_exception", "document missing")
+		}
+		return
+	}
+	if !f.matches(r, id) {
+		f.fail(w, http.StatusConflict, "version_conflict_engine_exception", "version conflict")
+		return
+	}
+
+	merged := map[string]interface{}{}
+	for field, value := range doc.source {
+		merged[field] = value
+	}
+	for field, value := range partial {
+		merged[field] = value
+	}
+	if reflect.DeepEqual(merged, doc.source) {
+		f.write(w, http.StatusOK, map[string]interface{}{
+			"_id": id, "_seq_no": doc.seqNo, "_primary_term": 1, "result": "noop",
+			"get": map[string]interface{}{"_source": doc.source},
+		})
+		return
+	}
+	f.store(w, http.StatusOK, "updated", id, merged)
+}
+
+func (f *fakeUsersCluster) get(w http.ResponseWriter, id string) {
+	doc, ok := f.docs[id]
+	if !ok {
+		f.write(w, http.StatusNotFound, map[string]interface{}{"_index": "users", "_id": id, "found": false})
+		return
+	}
+	f.write(w, http.StatusOK, map[string]interface{}{
+		"_index": "users", "_id": id, "found": true,
+		"_seq_no": doc.seqNo, "_primary_term": 1, "_source": doc.source,
+	})
+}
+
+func (f *fakeUsersCluster) remove(w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := f.docs[id]; !ok {
+		f.write(w, http.StatusNotFound, map[string]interface{}{"_index": "users", "_id": id, "result": "not_found"})
+		return
+	}
+	if !f.matches(r, id) {
+		f.fail(w, http.StatusConflict, "version_conflict_engine_exception", "version conflict")
+		return
+	}
+	delete(f.docs, id)
+	f.seqNo++
+	f.write(w, http.StatusOK, map[string]interface{}{"_id": id, "_seq_no": f.seqNo - 1, "_primary_term": 1, "result": "deleted"})
+}
+
+func (f *fakeUsersCluster) search(w http.ResponseWriter) {
+	hits := []interface{}{}
+	for id, doc := range f.docs {
+		hits = append(hits, map[string]interface{}{"_id": id, "_source": doc.source})
+	}
+	f.write(w, http.StatusOK, map[string]interface{}{
+		"hits": map[string]interface{}{"total": map[string]interface{}{"value": len(hits)}, "hits": hits},
+	})
+}
+
+// matches reports whether the if_seq_no and if_primary_term of r, if any, name the stored version
+func (f *fakeUsersCluster) matches(r *http.Request, id string) bool {
+	ifSeqNo := r.URL.Query().Get("if_seq_no")
+	if ifSeqNo == "" {
+		return true
+	}
+	doc, ok := f.docs[id]
+	return ok && ifSeqNo == strconv.Itoa(doc.seqNo) && r.URL.Query().Get("if_primary_term") == "1"
+}
+
+func (f *fakeUsersCluster) store(w http.ResponseWriter, status int, result, id string, source map[string]interface{}) {
+	f.docs[id] = fakeDocument{source: source, seqNo: f.seqNo}
+	f.seqNo++
+	f.write(w, status, map[string]interface{}{
+		"_index": "users", "_id": id, "_seq_no": f.seqNo - 1, "_primary_term": 1, "result": result,
+		"get": map[string]interface{}{"_source": source},
+	})
+}
+
+func (f *fakeUsersCluster) fail(w http.ResponseWriter, status int, errorType, reason string) {
+	f.write(w, status, map[string]interface{}{
+		"error":  map[string]interface{}{"type": errorType, "reason": reason},
+		"status": status,
+	})
+}
+
+func (f *fakeUsersCluster) write(w http.ResponseWriter, status int, body interface{}) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// newTestServer serves the users API in front of a fresh fake cluster
+func newTestServer(t *testing.T) http.Handler {
+	t.Helper()
+	cluster := httptest.NewServer(&fakeUsersCluster{})
+	t.Cleanup(cluster.Close)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ec, err := NewElasticsearchClient(Config{
+		Addresses: []string{cluster.URL},
+		Username:  "elastic",
+		Password:  "changeme",
+		Index:     "users",
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewUserServer(ec, logger).Handler()
+}
+
+// call sends a request to handler and returns the recorded response
+func call(handler http.Handler, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	r := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range header {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// errorCode returns the code of an error envelope
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var envelope struct {
+		Error struct {
+			Status int    `json:"status"`
+			Code   string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("error response %q: %v", w.Body.String(), err)
+	}
+	if envelope.Error.Status != w.Code {
+		t.Errorf("error status = %d, want %d", envelope.Error.Status, w.Code)
+	}
+	return envelope.Error.Code
+}
+
+const john = `{"id": "1", "name": "John", "email": "john@example.com"}`
+
+func TestCreate(t *testing.T) {
+	handler := newTestServer(t)
+
+	w := call(handler, http.MethodPost, "/users", john, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	if got := w.Header().Get("ETag"); got != `"0-1"` {
+		t.Errorf("ETag = %s, want \"0-1\"", got)
+	}
+	if got := w.Header().Get("Location"); got != "/users/1" {
+		t.Errorf("Location = %s, want /users/1", got)
+	}
+	var user User
+	if err := json.Unmarshal(w.Body.Bytes(), &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != "1" || user.Name != "John" || user.CreatedAt.IsZero() {
+		t.Errorf("user = %+v, want John with a creation time", user)
+	}
+
+	w = call(handler, http.MethodPost, "/users", john, nil)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("duplicate status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+	}
+	if code := errorCode(t, w); code != "conflict" {
+		t.Errorf("code = %s, want conflict", code)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	handler := newTestServer(t)
+
+	for _, tt := range []struct {
+		method, body string
+	}{
+		{http.MethodGet, ""},
+		{http.MethodPatch, `{"name": "Jane"}`},
+		{http.MethodDelete, ""},
+	} {
+		t.Run(tt.method, func(t *testing.T) {
+			w := call(handler, tt.method, "/users/404", tt.body, nil)
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body)
+			}
+			if code := errorCode(t, w); code != "not_found" {
+				t.Errorf("code = %s, want not_found", code)
+			}
+		})
+	}
+}
+
+func TestValidation(t *testing.T) {
+	handler := newTestServer(t)
+	if w := call(handler, http.MethodPost, "/users", john, nil); w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		name, method, target, body string
+	}{
+		{"missing name", http.MethodPost, "/users", `{"id": "2", "email": "jane@example.com"}`},
+		{"invalid email", http.MethodPost, "/users", `{"id": "2", "name": "Jane", "email": "jane"}`},
+		{"missing id", http.MethodPost, "/users", `{"name": "Jane", "email": "jane@example.com"}`},
+		{"id with underscore", http.MethodPost, "/users", `{"id": "_2", "name": "Jane", "email": "jane@example.com"}`},
+		{"unknown field", http.MethodPost, "/users", `{"id": "2", "name": "Jane", "email": "jane@example.com", "role": "admin"}`},
+		{"malformed JSON", http.MethodPost, "/users", `{"id": `},
+		{"empty patch", http.MethodPatch, "/users/1", `{}`},
+		{"patch with invalid email", http.MethodPatch, "/users/1", `{"email": "john"}`},
+		{"search without q", http.MethodGet, "/users", ""},
+		{"search size too large", http.MethodGet, "/users?q=john&size=1000", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := call(handler, tt.method, tt.target, tt.body, nil)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+			}
+			if code := errorCode(t, w); code != "invalid_request" {
+				t.Errorf("code = %s, want invalid_request", code)
+			}
+		})
+	}
+}
+
+func TestETag(t *testing.T) {
+	handler := newTestServer(t)
+
+	steps := []struct {
+		method, target, body string
+		status               int
+		etag                 string
+	}{
+		{http.MethodPost, "/users", john, http.StatusCreated, `"0-1"`},
+		{http.MethodGet, "/users/1", "", http.StatusOK, `"0-1"`},
+		{http.MethodPatch, "/users/1", `{"name": "Johnny"}`, http.StatusOK, `"1-1"`},
+		{http.MethodGet, "/users/1", "", http.StatusOK, `"1-1"`},
+	}
+	for _, step := range steps {
+		w := call(handler, step.method, step.target, step.body, nil)
+		if w.Code != step.status {
+			t.Fatalf("%s %s status = %d, want %d: %s", step.method, step.target, w.Code, step.status, w.Body)
+		}
+		if got := w.Header().Get("ETag"); got != step.etag {
+			t.Errorf("%s %s ETag = %s, want %s", step.method, step.target, got, step.etag)
+		}
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name, method, body, ifMatch string
+		status                      int
+	}{
+		{"patch at the current version", http.MethodPatch, `{"name": "Johnny"}`, `"0-1"`, http.StatusOK},
+		{"patch with any version", http.MethodPatch, `{"name": "Johnny"}`, `*`, http.StatusOK},
+		{"patch at a stale version", http.MethodPatch, `{"name": "Johnny"}`, `"5-1"`, http.StatusPreconditionFailed},
+		{"delete at the current version", http.MethodDelete, "", `"0-1"`, http.StatusNoContent},
+		{"delete at a stale version", http.MethodDelete, "", `"5-1"`, http.StatusPreconditionFailed},
+		{"delete at another primary term", http.MethodDelete, "", `"0-2"`, http.StatusPreconditionFailed},
+		{"patch with a list naming the current version", http.MethodPatch, `{"name": "Johnny"}`, `"5-1", "0-1"`, http.StatusOK},
+		{"patch with a list of stale versions", http.MethodPatch, `{"name": "Johnny"}`, `"5-1", "6-1"`, http.StatusPreconditionFailed},
+		{"patch with a weak tag", http.MethodPatch, `{"name": "Johnny"}`, `W/"0-1"`, http.StatusPreconditionFailed},
+		{"patch with a malformed tag", http.MethodPatch, `{"name": "Johnny"}`, `0-x`, http.StatusBadRequest},
+		{"delete with a list naming the current version", http.MethodDelete, "", `"5-1", "0-1"`, http.StatusNoContent},
+		{"delete with a list of stale versions", http.MethodDelete, "", `"5-1", W/"0-1"`, http.StatusPreconditionFailed},
+		{"delete with a weak tag", http.MethodDelete, "", `W/"0-1"`, http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestServer(t)
+			if w := call(handler, http.MethodPost, "/users", john, nil); w.Code != http.StatusCreated {
+				t.Fatalf("create status = %d: %s", w.Code, w.Body)
+			}
+
+			w := call(handler, tt.method, "/users/1", tt.body, map[string]string{"If-Match": tt.ifMatch})
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusPreconditionFailed {
+				if code := errorCode(t, w); code != "precondition_failed" {
+					t.Errorf("code = %s, want precondition_failed", code)
+				}
+			}
+		})
+	}
+}
+
+func TestIfNoneMatch(t *testing.T) {
+	handler := newTestServer(t)
+	if w := call(handler, http.MethodPost, "/users", john, nil); w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		ifNoneMatch string
+		status      int
+	}{
+		{`"0-1"`, http.StatusNotModified},
+		{`"3-1", "0-1"`, http.StatusNotModified},
+		{`*`, http.StatusNotModified},
+		{`"3-1"`, http.StatusOK},
+		{`W/"0-1"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ifNoneMatch, func(t *testing.T) {
+			w := call(handler, http.MethodGet, "/users/1", "", map[string]string{"If-None-Match": tt.ifNoneMatch})
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if got := w.Header().Get("ETag"); got != `"0-1"` {
+				t.Errorf("ETag = %s, want \"0-1\"", got)
+			}
+			if tt.status == http.StatusNotModified && w.Body.Len() > 0 {
+				t.Errorf("body = %q, want none", w.Body)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	handler := newTestServer(t)
+	for i, name := range []string{"John", "Jane"} {
+		body := fmt.Sprintf(`{"id": "%d", "name": "%s", "email": "%s@example.com"}`, i, name, strings.ToLower(name))
+		if w := call(handler, http.MethodPost, "/users", body, nil); w.Code != http.StatusCreated {
+			t.Fatalf("create status = %d: %s", w.Code, w.Body)
+		}
+	}
+
+	w := call(handler, http.MethodGet, "/users?q=example.com", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var result struct {
+		Users []User `json:"users"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Users) != 2 {
+		t.Errorf("users = %+v, want 2", result.Users)
+	}
+}
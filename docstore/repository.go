@@ -0,0 +1,463 @@
+// Package docstore stores Go values as documents of one Elasticsearch index. Every call is
+// retried, traced, measured and logged the same way whatever the document type.
+package docstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"Elastic-Search/breaker"
+	"Elastic-Search/eserrors"
+	"Elastic-Search/logging"
+	"Elastic-Search/metrics"
+	"Elastic-Search/retry"
+	"Elastic-Search/tracing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Version identifies a revision of a document. Writes made with a version only succeed
+// while the document is still at that revision.
+type Version struct {
+	SeqNo       int `json:"_seq_no"`
+	PrimaryTerm int `json:"_primary_term"`
+}
+
+// ETag formats the version as a strong entity tag, e.g. "12-1"
+func (v Version) ETag() string {
+	return fmt.Sprintf(`"%d-%d"`, v.SeqNo, v.PrimaryTerm)
+}
+
+// Document is a stored document along with its id and version
+type Document[T any] struct {
+	ID      string
+	Version Version
+	Source  T
+}
+
+// Config holds the settings of a Repository
+type Config[T any] struct {
+	Index     string           // Index holding the documents (required)
+	ID        func(T) string   // Returns the id of a document (required)
+	Name      string           // Name of a document in operation names and errors, defaults to "document"
+	Component string           // Component label of the metrics, defaults to "docstore"
+	Retry     *retry.Policies  // Optional: Retry policies per operation, defaults to retry.DefaultPolicies()
+	Breaker   *breaker.Breaker // Optional: Circuit breaker guarding the client's transport, reported by BreakerState
+	Metrics   *metrics.Metrics // Optional: Prometheus metrics for every operation
+	Logger    *slog.Logger     // Optional: Structured logger, defaults to slog.Default()
+	SlowQuery time.Duration    // Optional: Log request bodies of operations slower than this
+}
+
+// Repository stores documents of type T in one index
+type Repository[T any] struct {
+	client    *elasticsearch.Client
+	index     string
+	id        func(T) string
+	name      string
+	component string
+	retry     retry.Policies
+	breaker   *breaker.Breaker
+	metrics   *metrics.Metrics
+	logger    *slog.Logger
+	slowLog   *logging.SlowLog
+}
+
+// New creates a Repository storing documents through client
+func New[T any](client *elasticsearch.Client, config Config[T]) (*Repository[T], error) {
+	if config.Index == "" {
+		return nil, errors.New("docstore: index is required")
+	}
+	if config.ID == nil {
+		return nil, errors.New("docstore: id function is required")
+	}
+	r := &Repository[T]{
+		client:    client,
+		index:     config.Index,
+		id:        config.ID,
+		name:      config.Name,
+		component: config.Component,
+		retry:     retry.DefaultPolicies(),
+		breaker:   config.Breaker,
+		metrics:   config.Metrics,
+		logger:    logging.OrDefault(config.Logger),
+		slowLog:   &logging.SlowLog{Logger: config.Logger, Threshold: config.SlowQuery},
+	}
+	if r.name == "" {
+		r.name = "document"
+	}
+	if r.component == "" {
+		r.component = "docstore"
+	}
+	if config.Retry != nil {
+		r.retry = *config.Retry
+	}
+	return r, nil
+}
+
+// Index returns the index of the repository
+func (r *Repository[T]) Index() string {
+	return r.index
+}
+
+// BreakerState returns the state of the circuit breaker guarding the client's transport
+func (r *Repository[T]) BreakerState() breaker.State {
+	if r.breaker == nil {
+		return breaker.Closed
+	}
+	return r.breaker.State()
+}
+
+// Create indexes a new document and fails with eserrors.ErrVersionConflict if the id is taken
+func (r *Repository[T]) Create(ctx context.Context, doc T) (Version, error) {
+	op := r.op("create")
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return Version{}, fmt.Errorf("error marshalling %s: %w", r.name, err)
+	}
+
+	// Not idempotent: a lost response to a successful create turns the retry into a conflict
+	var version Version
+	err = r.do(ctx, op, false, body, func(ctx context.Context) (*esapi.Response, error) {
+		return r.client.Create(r.index, r.id(doc), bytes.NewReader(body), r.client.Create.WithContext(ctx))
+	}, decodeInto(&version))
+	return version, err
+}
+
+// Get returns a document along with its current version
+func (r *Repository[T]) Get(ctx context.Context, id string) (*T, Version, error) {
+	op := r.op("get")
+	var result struct {
+		Version
+		Source T `json:"_source"`
+	}
+	err := r.do(ctx, op, true, nil, func(ctx context.Context) (*esapi.Response, error) {
+		return r.client.Get(r.index, id, r.client.Get.WithContext(ctx))
+	}, decodeInto(&result))
+	if err != nil {
+		return nil, Version{}, err
+	}
+	return &result.Source, result.Version, nil
+}
+
+// UpdateOptions controls how Update changes a document
+type UpdateOptions[T any] struct {
+	// Only change the document while it is still at this version
+	IfMatch *Version
+	// Indexed as is when the document does not exist, e.g. a complete document with its creation time
+	Upsert *T
+	// Index the partial document itself when the document does not exist
+	DocAsUpsert bool
+	// Merge again up to this many times when the document changes concurrently. Cannot be
+	// combined with IfMatch, which fails on the first concurrent change instead.
+	RetryOnConflict int
+	// Optional: false writes and bumps the version even when the merge changes nothing.
+	// Elasticsearch detects such no-ops by default.
+	DetectNoop *bool
+}
+
+// UpdateResult is the document after an update
+type UpdateResult[T any] struct {
+	Source  T
+	Version Version
+	Result  string // created, updated or noop
+}
+
+// Noop reports whether the update left the document unchanged
+func (u *UpdateResult[T]) Noop() bool {
+	return u.Result == "noop"
+}
+
+// Update merges partial into the document with the id and returns the document as stored.
+// partial is any value that encodes to a JSON object with the fields to change, e.g. a T,
+// a struct of pointers with omitempty or a map. Fields missing from it are left as they are.
+// Without an upsert the document must exist.
+func (r *Repository[T]) Update(ctx context.Context, id string, partial interface{}, options UpdateOptions[T]) (*UpdateResult[T], error) {
+	op := r.op("update")
+	if options.IfMatch != nil && options.RetryOnConflict > 0 {
+		return nil, errors.New("docstore: IfMatch and RetryOnConflict cannot be combined")
+	}
+	if options.Upsert != nil && options.DocAsUpsert {
+		return nil, errors.New("docstore: Upsert and DocAsUpsert cannot be combined")
+	}
+
+	request := map[string]interface{}{"doc": partial}
+	if options.Upsert != nil {
+		request["upsert"] = options.Upsert
+	}
+	if options.DocAsUpsert {
+		request["doc_as_upsert"] = true
+	}
+	if options.DetectNoop != nil {
+		request["detect_noop"] = *options.DetectNoop
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling %s: %w", r.name, err)
+	}
+
+	var result struct {
+		Version
+		Result string `json:"result"`
+		Get    struct {
+			Source T `json:"_source"`
+		} `json:"get"`
+	}
+	// A conditional write is not idempotent: the retry of an applied write fails with a version conflict
+	err = r.do(ctx, op, options.IfMatch == nil, body, func(ctx context.Context) (*esapi.Response, error) {
+		requestOptions := []func(*esapi.UpdateRequest){
+			r.client.Update.WithContext(ctx),
+			r.client.Update.WithSource("true"),
+		}
+		if options.IfMatch != nil {
+			requestOptions = append(requestOptions,
+				r.client.Update.WithIfSeqNo(options.IfMatch.SeqNo),
+				r.client.Update.WithIfPrimaryTerm(options.IfMatch.PrimaryTerm),
+			)
+		}
+		if options.RetryOnConflict > 0 {
+			requestOptions = append(requestOptions, r.client.Update.WithRetryOnConflict(options.RetryOnConflict))
+		}
+		return r.client.Update(r.index, id, bytes.NewReader(body), requestOptions...)
+	}, decodeInto(&result))
+	if err != nil {
+		return nil, err
+	}
+	return &UpdateResult[T]{Source: result.Get.Source, Version: result.Version, Result: result.Result}, nil
+}
+
+// Upsert creates the document or replaces it entirely if it exists
+func (r *Repository[T]) Upsert(ctx context.Context, doc T) (Version, error) {
+	op := r.op("upsert")
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return Version{}, fmt.Errorf("error marshalling %s: %w", r.name, err)
+	}
+
+	var version Version
+	err = r.do(ctx, op, true, body, func(ctx context.Context) (*esapi.Response, error) {
+		return r.client.Index(
+			r.index,
+			bytes.NewReader(body),
+			r.client.Index.WithContext(ctx),
+			r.client.Index.WithDocumentID(r.id(doc)),
+		)
+	}, decodeInto(&version))
+	return version, err
+}
+
+// Delete deletes a document. When ifMatch is set the delete only succeeds at that version.
+func (r *Repository[T]) Delete(ctx context.Context, id string, ifMatch *Version) error {
+	op := r.op("delete")
+	// Like a conditional update, a conditional delete is not idempotent: the retry of an
+	// applied delete fails with a version conflict
+	return r.do(ctx, op, ifMatch == nil, nil, func(ctx context.Context) (*esapi.Response, error) {
+		options := []func(*esapi.DeleteRequest){r.client.Delete.WithContext(ctx)}
+		if ifMatch != nil {
+			options = append(options,
+				r.client.Delete.WithIfSeqNo(ifMatch.SeqNo),
+				r.client.Delete.WithIfPrimaryTerm(ifMatch.PrimaryTerm),
+			)
+		}
+		return r.client.Delete(r.index, id, options...)
+	}, nil)
+}
+
+// Exists reports whether a document with the id exists
+func (r *Repository[T]) Exists(ctx context.Context, id string) (bool, error) {
+	op := r.op("check")
+	err := r.do(ctx, op, true, nil, func(ctx context.Context) (*esapi.Response, error) {
+		return r.client.Exists(r.index, id, r.client.Exists.WithContext(ctx))
+	}, nil)
+	if errors.Is(err, eserrors.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// MultiGet returns the documents with the given ids that exist, in the order of ids
+func (r *Repository[T]) MultiGet(ctx context.Context, ids ...string) ([]Document[T], error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	op := r.op("multi get") + "s"
+	body, err := json.Marshal(map[string]interface{}{"ids": ids})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling ids: %w", err)
+	}
+
+	var result struct {
+		Docs []json.RawMessage `json:"docs"`
+	}
+	err = r.do(ctx, op, true, body, func(ctx context.Context) (*esapi.Response, error) {
+		return r.client.Mget(
+			bytes.NewReader(body),
+			r.client.Mget.WithContext(ctx),
+			r.client.Mget.WithIndex(r.index),
+		)
+	}, decodeInto(&result))
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make([]Document[T], 0, len(result.Docs))
+	for _, raw := range result.Docs {
+		var doc struct {
+			Version
+			ID     string          `json:"_id"`
+			Found  bool            `json:"found"`
+			Error  json.RawMessage `json:"error"`
+			Source T               `json:"_source"`
+		}
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return nil, fmt.Errorf("error parsing response: %w", err)
+		}
+		if doc.Error != nil {
+			// Failures of single documents, e.g. a missing index, come without a status
+			return nil, eserrors.Parse(op, http.StatusInternalServerError, raw)
+		}
+		if doc.Found {
+			documents = append(documents, Document[T]{ID: doc.ID, Version: doc.Version, Source: doc.Source})
+		}
+	}
+	return documents, nil
+}
+
+// Search returns the sources of the hits of query, a complete search request body
+func (r *Repository[T]) Search(ctx context.Context, query map[string]interface{}) ([]T, error) {
+	op := r.op("search") + "s"
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling search query: %w", err)
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source T `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	err = r.do(ctx, op, true, body, func(ctx context.Context) (*esapi.Response, error) {
+		return r.client.Search(
+			r.client.Search.WithContext(ctx),
+			r.client.Search.WithIndex(r.index),
+			r.client.Search.WithBody(bytes.NewReader(body)),
+		)
+	}, decodeInto(&result))
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]T, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		docs = append(docs, hit.Source)
+	}
+	return docs, nil
+}
+
+// op names an operation on a document of the repository, e.g. "create user".
+// The names key the retry policies and label metrics, spans and errors.
+func (r *Repository[T]) op(verb string) string {
+	return verb + " " + r.name
+}
+
+// do runs an Elasticsearch call under the retry policy of the operation in its own span,
+// records its metrics and logs it. body is the request body, if any, for the slow query log.
+// Error responses are returned as *eserrors.ESError; the body of a successful response is
+// passed to decode, if any. do closes the body.
+func (r *Repository[T]) do(
+	ctx context.Context,
+	op string,
+	idempotent bool,
+	body []byte,
+	fn func(ctx context.Context) (*esapi.Response, error),
+	decode func(body io.Reader) error,
+) error {
+	res, err := r.send(ctx, op, idempotent, body, fn)
+	if err != nil {
+		return fmt.Errorf("error executing %s: %w", op, err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			r.logger.Warn("error closing body", logging.KeyError, err)
+		}
+	}(res.Body)
+
+	if err := eserrors.FromResponse(op, res); err != nil {
+		return err
+	}
+	if decode == nil {
+		return nil
+	}
+	return decode(res.Body)
+}
+
+// send runs fn for do and observes it
+func (r *Repository[T]) send(
+	ctx context.Context,
+	op string,
+	idempotent bool,
+	body []byte,
+	fn func(ctx context.Context) (*esapi.Response, error),
+) (*esapi.Response, error) {
+	ctx, span := tracing.Start(ctx, op, r.index, "")
+	start := time.Now()
+	res, err := retry.Do(ctx, r.retry.For(op, idempotent), fn)
+	took := time.Since(start)
+	r.metrics.ObserveRequest(r.component, op, r.index, start, res, err)
+	r.logRequest(ctx, op, took, res, err)
+	r.slowLog.Observe(ctx, op, r.index, took, body)
+	if err == nil && res.IsError() {
+		span.SetAttributes(tracing.AttrStatusCode.Int(res.StatusCode))
+		span.SetStatus(codes.Error, res.Status())
+	}
+	tracing.End(span, err)
+	return res, err
+}
+
+// logRequest writes one line per operation with the fields shared by all Elasticsearch logs
+func (r *Repository[T]) logRequest(
+	ctx context.Context,
+	op string,
+	took time.Duration,
+	res *esapi.Response,
+	err error,
+) {
+	attrs := []slog.Attr{
+		slog.String(logging.KeyOperation, op),
+		slog.String(logging.KeyIndex, r.index),
+		slog.Duration(logging.KeyTook, took),
+	}
+	if res != nil {
+		attrs = append(attrs, slog.Int(logging.KeyStatus, res.StatusCode))
+	}
+	switch {
+	case err != nil:
+		attrs = append(attrs, slog.Any(logging.KeyError, err))
+		r.logger.LogAttrs(ctx, slog.LevelError, "request failed", attrs...)
+	case res.IsError():
+		r.logger.LogAttrs(ctx, slog.LevelWarn, "request returned an error", attrs...)
+	default:
+		r.logger.LogAttrs(ctx, slog.LevelDebug, "request", attrs...)
+	}
+}
+
+// decodeInto returns a decode function for do that reads the response into v
+func decodeInto(v interface{}) func(body io.Reader) error {
+	return func(body io.Reader) error {
+		if err := json.NewDecoder(body).Decode(v); err != nil {
+			return fmt.Errorf("error parsing response: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,125 @@
+package docstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"Elastic-Search/eserrors"
+	"Elastic-Search/internal/estest"
+	"Elastic-Search/retry"
+)
+
+// fakeTransport answers every request with the same status and counts the requests
+type fakeTransport struct {
+	status   int
+	body     string
+	requests int
+}
+
+func (f *fakeTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	f.requests++
+	return estest.Response(r, f.status, f.body), nil
+}
+
+type document struct {
+	ID string `json:"id"`
+}
+
+func newRepository(t *testing.T, transport *fakeTransport) *Repository[document] {
+	t.Helper()
+	client := estest.NewClient(t, transport)
+	policy := retry.DefaultPolicy()
+	policy.InitialBackoff, policy.MaxBackoff = time.Millisecond, time.Millisecond
+	repository, err := New(client, Config[document]{
+		Index:  "documents",
+		ID:     func(doc document) string { return doc.ID },
+		Retry:  &retry.Policies{Default: policy},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repository
+}
+
+func TestDeleteRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		ifMatch      *Version
+		wantRequests int
+	}{
+		{"unconditional delete is retried", nil, retry.DefaultPolicy().MaxAttempts},
+		{"conditional delete is not retried", &Version{SeqNo: 3, PrimaryTerm: 1}, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transport := &fakeTransport{
+				status: http.StatusServiceUnavailable,
+				body:   `{"error": {"type": "unavailable_shards_exception", "reason": "primary shard is not active"}, "status": 503}`,
+			}
+			repository := newRepository(t, transport)
+
+			err := repository.Delete(context.Background(), "1", test.ifMatch)
+			if !errors.Is(err, eserrors.ErrUnavailable) {
+				t.Fatalf("Delete() error = %v, want %v", err, eserrors.ErrUnavailable)
+			}
+			if transport.requests != test.wantRequests {
+				t.Errorf("sent %d requests, want %d", transport.requests, test.wantRequests)
+			}
+		})
+	}
+}
+
+func TestExists(t *testing.T) {
+	tests := []struct {
+		status  int
+		want    bool
+		wantErr bool
+	}{
+		{http.StatusOK, true, false},
+		{http.StatusNotFound, false, false},
+		{http.StatusUnauthorized, false, true},
+	}
+	for _, test := range tests {
+		t.Run(http.StatusText(test.status), func(t *testing.T) {
+			repository := newRepository(t, &fakeTransport{status: test.status})
+
+			exists, err := repository.Exists(context.Background(), "1")
+			if exists != test.want || (err != nil) != test.wantErr {
+				t.Errorf("Exists() = %v, %v, want %v and error %v", exists, err, test.want, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetDecodesDocument(t *testing.T) {
+	repository := newRepository(t, &fakeTransport{
+		status: http.StatusOK,
+		body:   `{"_index": "documents", "_id": "1", "_seq_no": 7, "_primary_term": 2, "found": true, "_source": {"id": "1"}}`,
+	})
+
+	doc, version, err := repository.Get(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if doc.ID != "1" || version != (Version{SeqNo: 7, PrimaryTerm: 2}) {
+		t.Errorf("Get() = %+v, %+v", doc, version)
+	}
+}
+
+func TestGetNotFound(t *testing.T) {
+	repository := newRepository(t, &fakeTransport{
+		status: http.StatusNotFound,
+		body:   `{"_index": "documents", "_id": "1", "found": false}`,
+	})
+
+	_, _, err := repository.Get(context.Background(), "1")
+	if !errors.Is(err, eserrors.ErrNotFound) {
+		t.Fatalf("Get() error = %v, want %v", err, eserrors.ErrNotFound)
+	}
+}
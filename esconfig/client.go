@@ -0,0 +1,209 @@
+package esconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"Elastic-Search/breaker"
+	"Elastic-Search/eserrors"
+	"Elastic-Search/logging"
+	"Elastic-Search/tracing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Transport tunes the HTTP connections to the cluster
+type Transport struct {
+	MaxIdleConnsPerHost   int           `json:"max_idle_conns_per_host" yaml:"max_idle_conns_per_host" toml:"max_idle_conns_per_host"`
+	MaxConnsPerHost       int           `json:"max_conns_per_host" yaml:"max_conns_per_host" toml:"max_conns_per_host"` // 0 is unlimited
+	DialTimeout           time.Duration `json:"dial_timeout" yaml:"dial_timeout" toml:"dial_timeout"`
+	ResponseHeaderTimeout time.Duration `json:"response_header_timeout" yaml:"response_header_timeout" toml:"response_header_timeout"` // 0 waits as long as the request context
+	IdleConnTimeout       time.Duration `json:"idle_conn_timeout" yaml:"idle_conn_timeout" toml:"idle_conn_timeout"`
+	CompressRequests      bool          `json:"compress_requests" yaml:"compress_requests" toml:"compress_requests"` // Gzip request bodies; responses are always accepted gzipped
+}
+
+// Discovery finds the other nodes of the cluster through the nodes info API. Leave it off
+// behind load balancers and on Elastic Cloud, where nodes are not directly reachable.
+type Discovery struct {
+	OnStart  bool          `json:"on_start" yaml:"on_start" toml:"on_start"`
+	Interval time.Duration `json:"interval" yaml:"interval" toml:"interval"` // 0 disables periodic discovery
+}
+
+// Startup controls the health check made when a client is created
+type Startup struct {
+	// WaitTimeout keeps retrying the check until the cluster is ready or the time is up.
+	// 0 makes a single attempt and fails fast.
+	WaitTimeout time.Duration `json:"wait_timeout" yaml:"wait_timeout" toml:"wait_timeout"`
+	// WaitForStatus is the minimum cluster health, green or yellow. Empty only requires the
+	// cluster to answer, which works with credentials that lack the monitor privilege.
+	WaitForStatus string `json:"wait_for_status,omitempty" yaml:"wait_for_status" toml:"wait_for_status"`
+}
+
+// DefaultTransport keeps more idle connections than net/http does by default, so concurrent
+// searches and bulk requests reuse connections instead of opening new ones.
+func DefaultTransport() Transport {
+	return Transport{
+		MaxIdleConnsPerHost: 32,
+		DialTimeout:         5 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+func (t Transport) validate() error {
+	if t.MaxIdleConnsPerHost < 0 || t.MaxConnsPerHost < 0 {
+		return errors.New("transport: connection limits must not be negative")
+	}
+	if t.DialTimeout < 0 || t.ResponseHeaderTimeout < 0 || t.IdleConnTimeout < 0 {
+		return errors.New("transport: timeouts must not be negative")
+	}
+	return nil
+}
+
+func (s Startup) validate() error {
+	switch s.WaitForStatus {
+	case "", "green", "yellow":
+	default:
+		return fmt.Errorf("startup: wait_for_status must be green or yellow, got %q", s.WaitForStatus)
+	}
+	if s.WaitTimeout < 0 {
+		return errors.New("startup: wait_timeout must not be negative")
+	}
+	return nil
+}
+
+// httpTransport returns the innermost transport with the TLS settings and tuning applied
+func (c *Config) httpTransport() (*http.Transport, error) {
+	transport, err := c.TLS.Transport()
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: c.Transport.DialTimeout, KeepAlive: 30 * time.Second}
+	transport.DialContext = dialer.DialContext
+	transport.MaxIdleConns = 0 // Bounded per host instead
+	transport.MaxIdleConnsPerHost = c.Transport.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = c.Transport.MaxConnsPerHost
+	transport.IdleConnTimeout = c.Transport.IdleConnTimeout
+	transport.ResponseHeaderTimeout = c.Transport.ResponseHeaderTimeout
+	return transport, nil
+}
+
+// ClientOptions are the parts of a client that belong to the component using it
+type ClientOptions struct {
+	Breaker *breaker.Breaker // Optional: Circuit breaker guarding the transport
+	Logger  *slog.Logger     // Optional: Logs the startup check, defaults to slog.Default()
+}
+
+// NewClient creates an Elasticsearch client with the authentication, TLS, transport and
+// discovery settings of c, and waits for the cluster as configured by c.Startup.
+// Every component of the module creates its clients here.
+func NewClient(ctx context.Context, c *Config, options ClientOptions) (*elasticsearch.Client, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	transport, err := c.httpTransport()
+	if err != nil {
+		return nil, fmt.Errorf("error configuring transport: %w", err)
+	}
+
+	// TLS lives on the innermost transport; the breaker and tracing only see plain requests
+	var roundTripper http.RoundTripper = transport
+	if options.Breaker != nil {
+		// Fail fast and shed load while the cluster is overloaded
+		roundTripper = options.Breaker.Transport(roundTripper)
+	}
+
+	username, password := c.BasicAuth()
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses:             c.ClientAddresses(),
+		CloudID:               c.CloudID,
+		Username:              username,
+		Password:              password,
+		APIKey:                c.APIKey.Value(),
+		Transport:             tracing.Transport(roundTripper),
+		CompressRequestBody:   c.Transport.CompressRequests,
+		DiscoverNodesOnStart:  c.Discovery.OnStart,
+		DiscoverNodesInterval: c.Discovery.Interval,
+		// Retries are handled by the retry package with backoff and per operation policies
+		DisableRetry: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating client: %w", err)
+	}
+
+	if err := WaitReady(ctx, client, c.Startup, options.Logger); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// WaitReady checks that the cluster answers, and has at least the health in
+// startup.WaitForStatus if set, retrying with backoff for up to startup.WaitTimeout
+func WaitReady(ctx context.Context, client *elasticsearch.Client, startup Startup, logger *slog.Logger) error {
+	logger = logging.OrDefault(logger)
+	if startup.WaitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, startup.WaitTimeout)
+		defer cancel()
+	}
+
+	backoff := 250 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err := checkReady(ctx, client, startup.WaitForStatus)
+		if err == nil {
+			if attempt > 1 {
+				logger.InfoContext(ctx, "cluster is ready", "attempts", attempt)
+			}
+			return nil
+		}
+		if startup.WaitTimeout <= 0 {
+			return fmt.Errorf("error connecting to Elasticsearch: %w", err)
+		}
+		// Bad credentials or missing privileges do not fix themselves
+		if errors.Is(err, eserrors.ErrUnauthorized) {
+			return fmt.Errorf("error connecting to Elasticsearch: %w", err)
+		}
+
+		logger.InfoContext(ctx, "waiting for cluster", "attempt", attempt, logging.KeyError, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("cluster not ready after %s: %w", startup.WaitTimeout, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 5*time.Second)
+	}
+}
+
+// checkReady makes one health check
+func checkReady(ctx context.Context, client *elasticsearch.Client, status string) error {
+	var res *esapi.Response
+	var err error
+	if status == "" {
+		res, err = client.Info(client.Info.WithContext(ctx))
+	} else {
+		options := []func(*esapi.ClusterHealthRequest){
+			client.Cluster.Health.WithContext(ctx),
+			client.Cluster.Health.WithWaitForStatus(status),
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			// Let the cluster hold the request until the status is reached instead of polling
+			options = append(options, client.Cluster.Health.WithTimeout(max(time.Until(deadline), time.Second)))
+		}
+		res, err = client.Cluster.Health(options...)
+	}
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	// A health request that timed out before reaching the status answers 408
+	return eserrors.FromResponse("check cluster health", res)
+}
@@ -0,0 +1,461 @@
+// Package esconfig loads the Elasticsearch connection settings shared by every command from
+// defaults, a YAML or TOML file, ES_* environment variables and flags, in increasing precedence.
+//
+// Secrets are held in Secret values, which print as [REDACTED] in logs, JSON and fmt output.
+package esconfig
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"Elastic-Search/logging"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Secret is a string that is never printed. Use Value to read it.
+type Secret string
+
+// Value returns the secret itself
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return logging.Redacted
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("%q", s.String())
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// Config holds the connection and logging settings of the commands
+type Config struct {
+	Addresses   []string      `json:"addresses" yaml:"addresses" toml:"addresses"`
+	CloudID     string        `json:"cloud_id,omitempty" yaml:"cloud_id" toml:"cloud_id"` // Used instead of Addresses when set
+	Username    string        `json:"username,omitempty" yaml:"username" toml:"username"`
+	Password    Secret        `json:"password,omitempty" yaml:"password" toml:"password"`
+	APIKey      Secret        `json:"api_key,omitempty" yaml:"api_key" toml:"api_key"` // Used instead of username and password when set
+	LogLevel    string        `json:"log_level" yaml:"log_level" toml:"log_level"`
+	MetricsAddr string        `json:"metrics_addr,omitempty" yaml:"metrics_addr" toml:"metrics_addr"`
+	SlowQuery   time.Duration `json:"slow_query" yaml:"slow_query" toml:"slow_query"`
+	TLS         TLS           `json:"tls" yaml:"tls" toml:"tls"`
+	Transport   Transport     `json:"transport" yaml:"transport" toml:"transport"`
+	Discovery   Discovery     `json:"discovery" yaml:"discovery" toml:"discovery"`
+	Startup     Startup       `json:"startup" yaml:"startup" toml:"startup"`
+}
+
+// Default returns the settings of a local cluster started with start-local, without credentials
+func Default() *Config {
+	return &Config{
+		Addresses: []string{"http://localhost:9200"},
+		Username:  "elastic",
+		LogLevel:  "info",
+		SlowQuery: time.Second,
+		Transport: DefaultTransport(),
+	}
+}
+
+// Level returns the parsed log level
+func (c *Config) Level() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return level, fmt.Errorf("invalid log_level %q: %w", c.LogLevel, err)
+	}
+	return level, nil
+}
+
+// Validate reports every invalid setting at once
+func (c *Config) Validate() error {
+	var errs []error
+	if c.CloudID != "" {
+		if _, err := CloudAddress(c.CloudID); err != nil {
+			errs = append(errs, err)
+		}
+	} else if len(c.Addresses) == 0 {
+		errs = append(errs, errors.New("addresses: at least one address is required"))
+	}
+	for _, address := range c.Addresses {
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("addresses: %q is not an http or https URL", address))
+			continue
+		}
+		if u.User != nil {
+			// Credentials in URLs end up in logs and error messages
+			errs = append(errs, fmt.Errorf("addresses: %q must not contain credentials, use username and password", u.Redacted()))
+		}
+	}
+	if c.Password != "" && c.Username == "" {
+		errs = append(errs, errors.New("password: username is required with a password"))
+	}
+	if _, err := c.Level(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.SlowQuery < 0 {
+		errs = append(errs, errors.New("slow_query: must not be negative"))
+	}
+	if err := c.TLS.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Transport.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Startup.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// ClientAddresses returns the addresses to pass to the Elasticsearch client, which
+// are none when the cluster is reached through its Cloud ID
+func (c *Config) ClientAddresses() []string {
+	if c.CloudID != "" {
+		return nil
+	}
+	return c.Addresses
+}
+
+// CloudAddress returns the Elasticsearch URL encoded in an Elastic Cloud ID of the form
+// name:base64(domain$elasticsearch-id$kibana-id)
+func CloudAddress(cloudID string) (string, error) {
+	_, encoded, ok := strings.Cut(cloudID, ":")
+	if !ok {
+		return "", errors.New("cloud_id: expected <name>:<base64>")
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("cloud_id: invalid base64: %w", err)
+	}
+	parts := strings.Split(string(data), "$")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", errors.New("cloud_id: expected a domain and an Elasticsearch id")
+	}
+	// The domain may carry a port, which stays at the end: https://<id>.<domain>[:port]
+	return "https://" + parts[1] + "." + parts[0], nil
+}
+
+// BasicAuth returns the username and password to send, which are empty when an API key is set
+func (c *Config) BasicAuth() (username, password string) {
+	if c.APIKey != "" {
+		return "", ""
+	}
+	return c.Username, c.Password.Value()
+}
+
+// Load returns the defaults overridden by the file at path, if not empty, and then by the
+// environment. getenv looks up environment variables, usually os.LookupEnv.
+func Load(path string, getenv func(string) (string, bool)) (*Config, error) {
+	c, err := load(path, getenv)
+	if err != nil {
+		return nil, err
+	}
+	return c, c.Validate()
+}
+
+func load(path string, getenv func(string) (string, bool)) (*Config, error) {
+	c := Default()
+	if path != "" {
+		if err := c.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.loadEnv(getenv); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// loadFile decodes a .yaml, .yml or .toml file over c. Unknown keys are errors so typos are not ignored.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("error parsing config file %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// Environment variables, with the start-local .env names as fallbacks
+const (
+	EnvConfig      = "ES_CONFIG"
+	EnvAddresses   = "ES_ADDRESSES" // Comma separated
+	EnvCloudID     = "ES_CLOUD_ID"
+	EnvUsername    = "ES_USERNAME"
+	EnvPassword    = "ES_PASSWORD"
+	EnvAPIKey      = "ES_API_KEY"
+	EnvLogLevel    = "ES_LOG_LEVEL"
+	EnvMetricsAddr = "ES_METRICS_ADDR"
+	EnvSlowQuery   = "ES_SLOW_QUERY"
+	EnvCAFile      = "ES_CA_FILE"
+	EnvCertFile    = "ES_CERT_FILE"
+	EnvKeyFile     = "ES_KEY_FILE"
+	EnvFingerprint = "ES_CA_FINGERPRINT"
+	EnvInsecure    = "ES_INSECURE" // true or false
+	EnvWaitTimeout = "ES_WAIT_TIMEOUT"
+	EnvWaitStatus  = "ES_WAIT_FOR_STATUS"
+
+	EnvLocalURL      = "ES_LOCAL_URL"
+	EnvLocalPassword = "ES_LOCAL_PASSWORD"
+	EnvLocalAPIKey   = "ES_LOCAL_API_KEY"
+)
+
+func (c *Config) loadEnv(getenv func(string) (string, bool)) error {
+	lookup := func(names ...string) (string, bool) {
+		for _, name := range names {
+			if value, ok := getenv(name); ok && value != "" {
+				return value, true
+			}
+		}
+		return "", false
+	}
+
+	if v, ok := lookup(EnvAddresses, EnvLocalURL); ok {
+		c.Addresses = splitList(v)
+	}
+	if v, ok := lookup(EnvCloudID); ok {
+		c.CloudID = v
+	}
+	if v, ok := lookup(EnvUsername); ok {
+		c.Username = v
+	}
+	if v, ok := lookup(EnvPassword, EnvLocalPassword); ok {
+		c.Password = Secret(v)
+	}
+	if v, ok := lookup(EnvAPIKey, EnvLocalAPIKey); ok {
+		c.APIKey = Secret(v)
+	}
+	if v, ok := lookup(EnvLogLevel); ok {
+		c.LogLevel = v
+	}
+	if v, ok := lookup(EnvMetricsAddr); ok {
+		c.MetricsAddr = v
+	}
+	if v, ok := lookup(EnvSlowQuery); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvSlowQuery, err)
+		}
+		c.SlowQuery = d
+	}
+	if v, ok := lookup(EnvCAFile); ok {
+		c.TLS.CAFile = v
+	}
+	if v, ok := lookup(EnvCertFile); ok {
+		c.TLS.CertFile = v
+	}
+	if v, ok := lookup(EnvKeyFile); ok {
+		c.TLS.KeyFile = v
+	}
+	if v, ok := lookup(EnvFingerprint); ok {
+		c.TLS.Fingerprint = v
+	}
+	if v, ok := lookup(EnvInsecure); ok {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvInsecure, err)
+		}
+		c.TLS.Insecure = insecure
+	}
+	if v, ok := lookup(EnvWaitTimeout); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvWaitTimeout, err)
+		}
+		c.Startup.WaitTimeout = d
+	}
+	if v, ok := lookup(EnvWaitStatus); ok {
+		c.Startup.WaitForStatus = v
+	}
+	return nil
+}
+
+// EnvFile returns a lookup function that reads the environment first and then the KEY=VALUE
+// lines of a dotenv file, such as the .env written by start-local
+func EnvFile(path string) (func(string) (string, bool), error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading env file: %w", err)
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading env file: %w", err)
+	}
+
+	return func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		value, ok := values[name]
+		return value, ok
+	}, nil
+}
+
+// Flags are the command line flags of a Config. Only flags that are set override the
+// file and the environment.
+type Flags struct {
+	fs          *flag.FlagSet
+	file        string
+	envFile     string
+	addresses   string
+	cloudID     string
+	username    string
+	password    string
+	apiKey      string
+	logLevel    string
+	metricsAddr string
+	slowQuery   time.Duration
+	tls         TLS
+	startup     Startup
+}
+
+// RegisterFlags registers the configuration flags on fs. Call Load after fs.Parse.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	d := Default()
+	f := &Flags{fs: fs}
+	fs.StringVar(&f.file, "config", "", "YAML or TOML config file; defaults to $"+EnvConfig)
+	fs.StringVar(&f.envFile, "env-file", "", "Read ES_* variables missing from the environment from this file, e.g. elastic-start-local/.env")
+	fs.StringVar(&f.addresses, "addresses", strings.Join(d.Addresses, ","), "Comma separated Elasticsearch addresses; $"+EnvAddresses+" or $"+EnvLocalURL)
+	fs.StringVar(&f.cloudID, "cloud-id", "", "Elastic Cloud ID, used instead of -addresses; $"+EnvCloudID)
+	fs.StringVar(&f.username, "username", d.Username, "Username for basic authentication; $"+EnvUsername)
+	fs.StringVar(&f.password, "password", "", "Password for basic authentication, prefer $"+EnvPassword+" or $"+EnvLocalPassword)
+	fs.StringVar(&f.apiKey, "api-key", "", "API key, used instead of username and password, prefer $"+EnvAPIKey+" or $"+EnvLocalAPIKey)
+	fs.StringVar(&f.logLevel, "log-level", d.LogLevel, "Log level: debug, info, warn or error; $"+EnvLogLevel)
+	fs.StringVar(&f.metricsAddr, "metrics-addr", "", "Serve Prometheus metrics on this address, e.g. :2112; $"+EnvMetricsAddr)
+	fs.DurationVar(&f.slowQuery, "slow-query", d.SlowQuery, "Log the body of requests slower than this; 0 disables; $"+EnvSlowQuery)
+	fs.StringVar(&f.tls.CAFile, "ca-file", "", "PEM file of CAs to trust for https addresses; $"+EnvCAFile)
+	fs.StringVar(&f.tls.CertFile, "cert-file", "", "PEM client certificate for mutual TLS; $"+EnvCertFile)
+	fs.StringVar(&f.tls.KeyFile, "key-file", "", "PEM private key of -cert-file; $"+EnvKeyFile)
+	fs.StringVar(&f.tls.Fingerprint, "ca-fingerprint", "", "Hex SHA-256 fingerprint of a certificate the cluster must present; $"+EnvFingerprint)
+	fs.DurationVar(&f.startup.WaitTimeout, "wait-ready", 0, "Wait up to this long for the cluster to be reachable; 0 fails at once; $"+EnvWaitTimeout)
+	fs.StringVar(&f.startup.WaitForStatus, "wait-for-status", "", "Also wait for this cluster health: green or yellow; $"+EnvWaitStatus)
+	fs.BoolVar(&f.tls.Insecure, "insecure", false, "Do not verify the TLS certificate of the cluster, for development only; $"+EnvInsecure)
+	return f
+}
+
+// Load loads the configuration and overrides it with the flags set on the command line
+func (f *Flags) Load() (*Config, error) {
+	getenv := os.LookupEnv
+	if f.envFile != "" {
+		var err error
+		if getenv, err = EnvFile(f.envFile); err != nil {
+			return nil, err
+		}
+	}
+	path := f.file
+	if path == "" {
+		path, _ = getenv(EnvConfig)
+	}
+
+	c, err := load(path, getenv)
+	if err != nil {
+		return nil, err
+	}
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "addresses":
+			c.Addresses = splitList(f.addresses)
+		case "cloud-id":
+			c.CloudID = f.cloudID
+		case "username":
+			c.Username = f.username
+		case "password":
+			c.Password = Secret(f.password)
+		case "api-key":
+			c.APIKey = Secret(f.apiKey)
+		case "log-level":
+			c.LogLevel = f.logLevel
+		case "metrics-addr":
+			c.MetricsAddr = f.metricsAddr
+		case "slow-query":
+			c.SlowQuery = f.slowQuery
+		case "ca-file":
+			c.TLS.CAFile = f.tls.CAFile
+		case "cert-file":
+			c.TLS.CertFile = f.tls.CertFile
+		case "key-file":
+			c.TLS.KeyFile = f.tls.KeyFile
+		case "ca-fingerprint":
+			c.TLS.Fingerprint = f.tls.Fingerprint
+		case "insecure":
+			c.TLS.Insecure = f.tls.Insecure
+		case "wait-ready":
+			c.Startup.WaitTimeout = f.startup.WaitTimeout
+		case "wait-for-status":
+			c.Startup.WaitForStatus = f.startup.WaitForStatus
+		}
+	})
+	return c, c.Validate()
+}
+
+// splitList splits a comma separated list and drops empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
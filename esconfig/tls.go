@@ -0,0 +1,114 @@
+package esconfig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// TLS holds the TLS settings of https connections. The zero value verifies the
+// cluster against the system roots.
+type TLS struct {
+	CAFile   string `json:"ca_file" yaml:"ca_file" toml:"ca_file"`       // PEM bundle of CAs trusted in addition to the system roots
+	CertFile string `json:"cert_file" yaml:"cert_file" toml:"cert_file"` // PEM client certificate for mutual TLS
+	KeyFile  string `json:"key_file" yaml:"key_file" toml:"key_file"`    // PEM private key of CertFile
+	// SHA-256 fingerprint, in hex, of a certificate in the chain of the cluster, as printed by
+	// Elasticsearch on first start. Alone it replaces chain verification; with CAFile both must pass.
+	Fingerprint string `json:"ca_fingerprint" yaml:"ca_fingerprint" toml:"ca_fingerprint"`
+	Insecure    bool   `json:"insecure" yaml:"insecure" toml:"insecure"` // Skip all verification. Only for development.
+}
+
+// Validate reports settings that cannot work together
+func (t TLS) Validate() error {
+	var errs []error
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, errors.New("tls: cert_file and key_file must be set together"))
+	}
+	if t.Fingerprint != "" {
+		if _, err := t.fingerprint(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if t.Insecure && (t.CAFile != "" || t.Fingerprint != "") {
+		errs = append(errs, errors.New("tls: insecure cannot be combined with ca_file or ca_fingerprint"))
+	}
+	return errors.Join(errs...)
+}
+
+// fingerprint decodes Fingerprint, accepting upper or lower case and colon separated bytes
+func (t TLS) fingerprint() ([]byte, error) {
+	digest, err := hex.DecodeString(strings.ReplaceAll(t.Fingerprint, ":", ""))
+	if err != nil || len(digest) != sha256.Size {
+		return nil, fmt.Errorf("tls: ca_fingerprint must be a hex SHA-256 digest, got %d characters", len(t.Fingerprint))
+	}
+	return digest, nil
+}
+
+// ClientConfig returns the crypto/tls configuration of the settings
+func (t TLS) ClientConfig() (*tls.Config, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA file: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in CA file %s", t.CAFile)
+		}
+		config.RootCAs = roots
+	}
+
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if t.Fingerprint != "" {
+		pinned, _ := t.fingerprint()
+		// Without a CA the chain cannot be verified, so the pin is the only check
+		config.InsecureSkipVerify = t.CAFile == ""
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			for _, cert := range state.PeerCertificates {
+				digest := sha256.Sum256(cert.Raw)
+				if bytes.Equal(digest[:], pinned) {
+					return nil
+				}
+			}
+			return errors.New("tls: no certificate presented by the cluster matches ca_fingerprint")
+		}
+	}
+
+	if t.Insecure {
+		config.InsecureSkipVerify = true
+	}
+	return config, nil
+}
+
+// Transport returns a clone of http.DefaultTransport using the TLS settings
+func (t TLS) Transport() (*http.Transport, error) {
+	config, err := t.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	return transport, nil
+}
@@ -0,0 +1,229 @@
+package esconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTLSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// writePEM writes one PEM block to a file in a temporary directory and returns its path
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// serverCAFile writes the certificate of server, which is self-signed, as a CA bundle
+func serverCAFile(t *testing.T, server *httptest.Server) string {
+	return writePEM(t, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+}
+
+// get makes one request to server through the transport of settings
+func get(t *testing.T, settings TLS, server *httptest.Server) error {
+	t.Helper()
+	transport, err := settings.Transport()
+	if err != nil {
+		t.Fatalf("Transport() error = %v", err)
+	}
+	defer transport.CloseIdleConnections()
+	res, err := (&http.Client{Transport: transport, Timeout: 5 * time.Second}).Get(server.URL)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+	return nil
+}
+
+func TestTLSCAFile(t *testing.T) {
+	server := newTLSServer(t)
+
+	if err := get(t, TLS{}, server); err == nil {
+		t.Fatal("request to a server with an unknown CA succeeded")
+	}
+	if err := get(t, TLS{CAFile: serverCAFile(t, server)}, server); err != nil {
+		t.Fatalf("request with the CA of the server failed: %v", err)
+	}
+}
+
+func TestTLSCAFileWithoutCertificates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(path, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (TLS{CAFile: path}).ClientConfig(); err == nil {
+		t.Fatal("ClientConfig() accepted a CA file without certificates")
+	}
+}
+
+// newClientCertificate creates a CA and a client certificate signed by it, and returns
+// a pool with the CA and the paths of the certificate and its key
+func newClientCertificate(t *testing.T) (*x509.CertPool, string, string) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test client CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTemplate, ca, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	return pool, writePEM(t, "client.pem", "CERTIFICATE", clientDER), writePEM(t, "client-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+func TestTLSMutual(t *testing.T) {
+	clientCAs, certFile, keyFile := newClientCertificate(t)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			t.Error("request without a client certificate")
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	caFile := serverCAFile(t, server)
+
+	if err := get(t, TLS{CAFile: caFile}, server); err == nil {
+		t.Fatal("request without a client certificate succeeded")
+	}
+	if err := get(t, TLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}, server); err != nil {
+		t.Fatalf("request with a client certificate failed: %v", err)
+	}
+}
+
+func TestTLSFingerprint(t *testing.T) {
+	server := newTLSServer(t)
+	digest := sha256.Sum256(server.Certificate().Raw)
+	fingerprint := hex.EncodeToString(digest[:])
+
+	tests := []struct {
+		name        string
+		settings    TLS
+		wantSuccess bool
+	}{
+		{"match", TLS{Fingerprint: fingerprint}, true},
+		{"match upper case with colons", TLS{Fingerprint: colonSeparated(strings.ToUpper(fingerprint))}, true},
+		{"match with CA file", TLS{Fingerprint: fingerprint, CAFile: serverCAFile(t, server)}, true},
+		{"mismatch", TLS{Fingerprint: strings.Repeat("ab", sha256.Size)}, false},
+		{"mismatch with CA file", TLS{Fingerprint: strings.Repeat("ab", sha256.Size), CAFile: serverCAFile(t, server)}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := get(t, test.settings, server)
+			if test.wantSuccess && err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if !test.wantSuccess && err == nil {
+				t.Fatal("request succeeded with a wrong fingerprint")
+			}
+		})
+	}
+}
+
+func colonSeparated(hexDigest string) string {
+	pairs := make([]string, 0, len(hexDigest)/2)
+	for i := 0; i < len(hexDigest); i += 2 {
+		pairs = append(pairs, hexDigest[i:i+2])
+	}
+	return strings.Join(pairs, ":")
+}
+
+func TestTLSInsecure(t *testing.T) {
+	server := newTLSServer(t)
+
+	if err := get(t, TLS{Insecure: true}, server); err != nil {
+		t.Fatalf("insecure request failed: %v", err)
+	}
+	for _, settings := range []TLS{
+		{Insecure: true, CAFile: serverCAFile(t, server)},
+		{Insecure: true, Fingerprint: strings.Repeat("ab", sha256.Size)},
+	} {
+		if err := settings.Validate(); err == nil {
+			t.Errorf("Validate(%+v) accepted insecure with verification settings", settings)
+		}
+		if _, err := settings.Transport(); err == nil {
+			t.Errorf("Transport(%+v) accepted insecure with verification settings", settings)
+		}
+	}
+}
+
+func TestTLSValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings TLS
+		wantErr  bool
+	}{
+		{"zero value", TLS{}, false},
+		{"cert without key", TLS{CertFile: "client.pem"}, true},
+		{"key without cert", TLS{KeyFile: "client-key.pem"}, true},
+		{"short fingerprint", TLS{Fingerprint: "abcd"}, true},
+		{"fingerprint not hex", TLS{Fingerprint: strings.Repeat("zz", sha256.Size)}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.settings.Validate(); (err != nil) != test.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
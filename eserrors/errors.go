@@ -0,0 +1,173 @@
+// Package eserrors turns Elasticsearch error responses into typed errors that
+// callers can inspect with errors.Is and errors.As.
+package eserrors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Sentinel errors matched by *ESError through errors.Is
+var (
+	ErrNotFound        = errors.New("elasticsearch: not found")
+	ErrIndexNotFound   = errors.New("elasticsearch: index not found")
+	ErrVersionConflict = errors.New("elasticsearch: version conflict")
+	ErrTooManyRequests = errors.New("elasticsearch: too many requests")
+	ErrMappingConflict = errors.New("elasticsearch: mapping conflict")
+	ErrBadRequest      = errors.New("elasticsearch: bad request")
+	ErrUnauthorized    = errors.New("elasticsearch: unauthorized")
+	ErrUnavailable     = errors.New("elasticsearch: unavailable")
+)
+
+// Cause is one entry of the root_cause list of an Elasticsearch error
+type Cause struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+	Index  string `json:"index,omitempty"`
+}
+
+// ESError is an error response returned by Elasticsearch
+type ESError struct {
+	Op        string  // Operation that failed, e.g. "search" or "create user"
+	Status    int     // HTTP status code
+	Type      string  // Error type, e.g. "index_not_found_exception"
+	Reason    string  // Human readable reason
+	Index     string  // Index the error refers to, if any
+	RootCause []Cause // Underlying causes reported by the cluster
+}
+
+func (e *ESError) Error() string {
+	var b strings.Builder
+	if e.Op != "" {
+		b.WriteString(e.Op)
+		b.WriteString(": ")
+	}
+	fmt.Fprintf(&b, "[%d %s]", e.Status, http.StatusText(e.Status))
+	if e.Type != "" {
+		fmt.Fprintf(&b, " %s", e.Type)
+	}
+	if e.Reason != "" {
+		fmt.Fprintf(&b, ": %s", e.Reason)
+	}
+	return b.String()
+}
+
+// Is reports whether the error matches one of the sentinel errors of this package
+func (e *ESError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case ErrIndexNotFound:
+		return e.hasType("index_not_found_exception")
+	case ErrVersionConflict:
+		return e.Status == http.StatusConflict || e.hasType("version_conflict_engine_exception")
+	case ErrTooManyRequests:
+		return e.Status == http.StatusTooManyRequests || e.hasType("es_rejected_execution_exception")
+	case ErrMappingConflict:
+		return e.hasType(
+			"mapper_parsing_exception",
+			"document_parsing_exception",
+			"strict_dynamic_mapping_exception",
+		)
+	case ErrBadRequest:
+		return e.Status == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.Status == http.StatusUnauthorized || e.Status == http.StatusForbidden
+	case ErrUnavailable:
+		return e.Status == http.StatusBadGateway ||
+			e.Status == http.StatusServiceUnavailable ||
+			e.Status == http.StatusGatewayTimeout
+	}
+	return false
+}
+
+// hasType reports whether the error or any of its root causes has one of the given types
+func (e *ESError) hasType(types ...string) bool {
+	for _, t := range types {
+		if e.Type == t {
+			return true
+		}
+		for _, cause := range e.RootCause {
+			if cause.Type == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// errorBody is the JSON body of an Elasticsearch error response.
+// The error field is usually an object but some endpoints return a plain string.
+type errorBody struct {
+	Error  json.RawMessage `json:"error"`
+	Status int             `json:"status"`
+}
+
+type errorDetail struct {
+	Type      string  `json:"type"`
+	Reason    string  `json:"reason"`
+	Index     string  `json:"index"`
+	RootCause []Cause `json:"root_cause"`
+}
+
+// Parse builds an *ESError from a status code and an error response body
+func Parse(op string, status int, body []byte) *ESError {
+	esErr := &ESError{Op: op, Status: status}
+
+	var parsed errorBody
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		esErr.Reason = strings.TrimSpace(string(body))
+		return esErr
+	}
+	if parsed.Status != 0 {
+		esErr.Status = parsed.Status
+	}
+
+	var detail errorDetail
+	var reason string
+	switch {
+	case len(parsed.Error) == 0:
+		// e.g. a GET of a missing document: {"_index":"users","_id":"3","found":false}
+	case json.Unmarshal(parsed.Error, &detail) == nil:
+		esErr.Type = detail.Type
+		esErr.Reason = detail.Reason
+		esErr.Index = detail.Index
+		esErr.RootCause = detail.RootCause
+	case json.Unmarshal(parsed.Error, &reason) == nil:
+		esErr.Reason = reason
+	}
+
+	if esErr.Index == "" {
+		for _, cause := range esErr.RootCause {
+			if cause.Index != "" {
+				esErr.Index = cause.Index
+				break
+			}
+		}
+	}
+	return esErr
+}
+
+// FromResponse returns nil for successful responses and an *ESError otherwise.
+// The response body is consumed; callers remain responsible for closing it.
+func FromResponse(op string, res *esapi.Response) error {
+	if res == nil || !res.IsError() {
+		return nil
+	}
+
+	var body []byte
+	if res.Body != nil {
+		var err error
+		body, err = io.ReadAll(res.Body)
+		if err != nil {
+			return fmt.Errorf("%s: error reading error response: %w", op, err)
+		}
+	}
+	return Parse(op, res.StatusCode, body)
+}
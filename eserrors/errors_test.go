@@ -0,0 +1,169 @@
+package eserrors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantStatus int
+		wantType   string
+		wantReason string
+		wantIndex  string
+	}{
+		{
+			"object error",
+			http.StatusNotFound,
+			`{"error": {"root_cause": [{"type": "index_not_found_exception", "reason": "no such index [products]", "index": "products"}], "type": "index_not_found_exception", "reason": "no such index [products]", "index": "products"}, "status": 404}`,
+			http.StatusNotFound, "index_not_found_exception", "no such index [products]", "products",
+		},
+		{
+			"string error",
+			http.StatusBadRequest,
+			`{"error": "Incorrect HTTP method for uri [/products/_doc]", "status": 405}`,
+			http.StatusMethodNotAllowed, "", "Incorrect HTTP method for uri [/products/_doc]", "",
+		},
+		{
+			"missing document",
+			http.StatusNotFound,
+			`{"_index": "users", "_id": "3", "found": false}`,
+			http.StatusNotFound, "", "", "",
+		},
+		{"empty body of a HEAD request", http.StatusNotFound, ``, http.StatusNotFound, "", "", ""},
+		{"body that is not JSON", http.StatusBadGateway, "upstream connect error\n", http.StatusBadGateway, "", "upstream connect error", ""},
+		{
+			"status of the body overrides the response status",
+			http.StatusOK,
+			`{"error": {"type": "search_phase_execution_exception", "reason": "all shards failed"}, "status": 503}`,
+			http.StatusServiceUnavailable, "search_phase_execution_exception", "all shards failed", "",
+		},
+		{
+			"index of a root cause",
+			http.StatusBadRequest,
+			`{"error": {"root_cause": [{"type": "mapper_parsing_exception", "reason": "failed to parse", "index": "users"}], "type": "mapper_parsing_exception", "reason": "failed to parse"}, "status": 400}`,
+			http.StatusBadRequest, "mapper_parsing_exception", "failed to parse", "users",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Parse("search", test.status, []byte(test.body))
+			if got.Op != "search" || got.Status != test.wantStatus || got.Type != test.wantType ||
+				got.Reason != test.wantReason || got.Index != test.wantIndex {
+				t.Errorf("Parse() = %+v, want status %d, type %q, reason %q and index %q",
+					got, test.wantStatus, test.wantType, test.wantReason, test.wantIndex)
+			}
+		})
+	}
+}
+
+func TestIs(t *testing.T) {
+	sentinels := []error{
+		ErrNotFound,
+		ErrIndexNotFound,
+		ErrVersionConflict,
+		ErrTooManyRequests,
+		ErrMappingConflict,
+		ErrBadRequest,
+		ErrUnauthorized,
+		ErrUnavailable,
+	}
+	tests := []struct {
+		name string
+		err  *ESError
+		want []error
+	}{
+		{"missing document", &ESError{Status: http.StatusNotFound}, []error{ErrNotFound}},
+		{
+			"missing index",
+			&ESError{Status: http.StatusNotFound, Type: "index_not_found_exception"},
+			[]error{ErrNotFound, ErrIndexNotFound},
+		},
+		{
+			"missing index in a root cause",
+			&ESError{
+				Status:    http.StatusBadRequest,
+				Type:      "search_phase_execution_exception",
+				RootCause: []Cause{{Type: "index_not_found_exception"}},
+			},
+			[]error{ErrIndexNotFound, ErrBadRequest},
+		},
+		{"version conflict", &ESError{Status: http.StatusConflict}, []error{ErrVersionConflict}},
+		{"rejected execution", &ESError{Status: http.StatusTooManyRequests}, []error{ErrTooManyRequests}},
+		{
+			"rejected execution in a root cause",
+			&ESError{Status: http.StatusInternalServerError, RootCause: []Cause{{Type: "es_rejected_execution_exception"}}},
+			[]error{ErrTooManyRequests},
+		},
+		{
+			"mapping conflict in a root cause",
+			&ESError{
+				Status:    http.StatusBadRequest,
+				Type:      "illegal_argument_exception",
+				RootCause: []Cause{{Type: "document_parsing_exception"}},
+			},
+			[]error{ErrMappingConflict, ErrBadRequest},
+		},
+		{"strict mapping", &ESError{Status: http.StatusBadRequest, Type: "strict_dynamic_mapping_exception"}, []error{ErrMappingConflict, ErrBadRequest}},
+		{"unauthorized", &ESError{Status: http.StatusUnauthorized}, []error{ErrUnauthorized}},
+		{"forbidden", &ESError{Status: http.StatusForbidden}, []error{ErrUnauthorized}},
+		{"bad gateway", &ESError{Status: http.StatusBadGateway}, []error{ErrUnavailable}},
+		{"service unavailable", &ESError{Status: http.StatusServiceUnavailable}, []error{ErrUnavailable}},
+		{"gateway timeout", &ESError{Status: http.StatusGatewayTimeout}, []error{ErrUnavailable}},
+		{"server error", &ESError{Status: http.StatusInternalServerError}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Callers see the error wrapped with context
+			err := fmt.Errorf("error getting user: %w", test.err)
+			for _, sentinel := range sentinels {
+				if got, want := errors.Is(err, sentinel), slices.Contains(test.want, sentinel); got != want {
+					t.Errorf("errors.Is(%v, %v) = %v, want %v", err, sentinel, got, want)
+				}
+			}
+			var esErr *ESError
+			if !errors.As(err, &esErr) || esErr != test.err {
+				t.Errorf("errors.As(%v) did not find the *ESError", err)
+			}
+		})
+	}
+}
+
+func TestFromResponse(t *testing.T) {
+	response := func(status int, body string) *esapi.Response {
+		return &esapi.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+	}
+
+	if err := FromResponse("get user", response(http.StatusOK, `{"found": true}`)); err != nil {
+		t.Errorf("FromResponse() of a success = %v, want nil", err)
+	}
+	if err := FromResponse("get user", nil); err != nil {
+		t.Errorf("FromResponse() of no response = %v, want nil", err)
+	}
+
+	err := FromResponse("create user", response(http.StatusConflict,
+		`{"error": {"type": "version_conflict_engine_exception", "reason": "[1]: version conflict, document already exists"}, "status": 409}`))
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("FromResponse() = %v, want %v", err, ErrVersionConflict)
+	}
+	want := "create user: [409 Conflict] version_conflict_engine_exception: [1]: version conflict, document already exists"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	// HEAD requests have no body
+	res := &esapi.Response{StatusCode: http.StatusNotFound, Body: http.NoBody}
+	if err := FromResponse("user exists", res); !errors.Is(err, ErrNotFound) || errors.Is(err, ErrIndexNotFound) {
+		t.Errorf("FromResponse() of an empty 404 = %v, want %v only", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,209 @@
+// Package httpapi holds the error envelope, request decoding and server setup shared by
+// the REST APIs of the search and user services.
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"Elastic-Search/breaker"
+	"Elastic-Search/eserrors"
+	"Elastic-Search/logging"
+)
+
+// DefaultMaxBodyBytes bounds request bodies of an API that sets no MaxBodyBytes
+const DefaultMaxBodyBytes = 1 << 20
+
+// ErrorResponse is the envelope of every error returned by the APIs
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+// Error describes what went wrong, Code is stable for clients to match on
+type Error struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ErrInvalid matches the errors of requests that fail validation
+var ErrInvalid = errors.New("invalid request")
+
+// ValidationError is a client mistake in the request
+type ValidationError struct {
+	message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.message
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// Invalid returns a *ValidationError with a formatted message
+func Invalid(format string, args ...interface{}) error {
+	return &ValidationError{message: fmt.Sprintf(format, args...)}
+}
+
+// API holds the settings of one REST API
+type API struct {
+	Name   string       // Name of the API in log messages, e.g. "users API"
+	Logger *slog.Logger // Optional: Structured logger, defaults to slog.Default()
+	// Optional: Maps the errors specific to the API to a status and code. A zero status
+	// leaves the error to the mapping of Status.
+	Status func(err error) (int, string)
+	// Optional: Message of 404 responses, e.g. to not tell which lookup failed
+	NotFoundMessage       string
+	MaxBodyBytes          int64 // Optional: Limit of request bodies, defaults to DefaultMaxBodyBytes
+	DisallowUnknownFields bool  // Reject request bodies with fields v does not have
+}
+
+// Decode decodes a JSON request body into v, rejecting oversized bodies
+func (a *API) Decode(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/json") {
+		return Invalid("Content-Type must be application/json")
+	}
+	maxBytes := a.MaxBodyBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes))
+	if a.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return Invalid("request body must not exceed %d bytes", maxBytes)
+		}
+		return Invalid("invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+// WriteError maps an error to a status code and writes it in the error envelope
+func (a *API) WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := a.errorStatus(err)
+
+	var rejected *breaker.RejectedError
+	if errors.As(err, &rejected) && rejected.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rejected.RetryAfter.Seconds()))))
+	}
+
+	message := err.Error()
+	switch {
+	case status == http.StatusNotFound && a.NotFoundMessage != "":
+		message = a.NotFoundMessage
+	case status >= http.StatusInternalServerError:
+		logging.OrDefault(a.Logger).ErrorContext(r.Context(), a.Name+" request failed",
+			"path", r.URL.Path,
+			logging.KeyStatus, status,
+			logging.KeyError, err,
+		)
+		// Details of upstream failures stay in the logs
+		if status == http.StatusInternalServerError {
+			message = "internal error"
+		}
+	}
+	WriteJSON(w, status, ErrorResponse{Error: Error{Status: status, Code: code, Message: message}})
+}
+
+func (a *API) errorStatus(err error) (int, string) {
+	var validation *ValidationError
+	if errors.As(err, &validation) {
+		return http.StatusBadRequest, "invalid_request"
+	}
+	if a.Status != nil {
+		if status, code := a.Status(err); status != 0 {
+			return status, code
+		}
+	}
+	return Status(err)
+}
+
+// Status maps validation, Elasticsearch and client side errors shared by the APIs to HTTP statuses
+func Status(err error) (int, string) {
+	var validation *ValidationError
+	var esErr *eserrors.ESError
+	switch {
+	case errors.As(err, &validation):
+		return http.StatusBadRequest, "invalid_request"
+	case errors.Is(err, eserrors.ErrTooManyRequests):
+		return http.StatusTooManyRequests, "too_many_requests"
+	case errors.Is(err, breaker.ErrOpen), errors.Is(err, breaker.ErrTooManyInFlight):
+		return http.StatusServiceUnavailable, "overloaded"
+	case errors.Is(err, eserrors.ErrUnavailable):
+		return http.StatusServiceUnavailable, "unavailable"
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "timeout"
+	case errors.As(err, &esErr):
+		return http.StatusBadGateway, "upstream_error"
+	}
+	return http.StatusInternalServerError, "internal"
+}
+
+// Health returns a handler reporting the API as up along with the state of its circuit breaker
+func Health(state func() breaker.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]string{
+			"status":  "ok",
+			"breaker": state().String(),
+		})
+	}
+}
+
+// ListenAndServe serves handler on addr until ctx is cancelled, then shuts it down gracefully
+func (a *API) ListenAndServe(ctx context.Context, addr string, handler http.Handler) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return a.Serve(ctx, listener, handler)
+}
+
+// Serve is ListenAndServe on a bound listener, so callers can report a failed bind before
+// serving in the background
+func (a *API) Serve(ctx context.Context, listener net.Listener, handler http.Handler) error {
+	logger := logging.OrDefault(a.Logger)
+	addr := listener.Addr().String()
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error("error shutting down "+a.Name, logging.KeyError, err)
+		}
+	}()
+
+	logger.Info("serving "+a.Name, "addr", addr)
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// WriteJSON writes v as the JSON body of a response with status
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
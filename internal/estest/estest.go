@@ -0,0 +1,37 @@
+// Package estest fakes the Elasticsearch cluster behind a client in tests.
+package estest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// Response returns the answer of Elasticsearch to r with status and a JSON body
+func Response(r *http.Request, status int, body string) *http.Response {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	// Without it the client refuses the response as not coming from Elasticsearch
+	header.Set("X-Elastic-Product", "Elasticsearch")
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    r,
+	}
+}
+
+// NewClient returns a client whose requests are answered by transport. It does
+// not retry, so the retries under test are those of the code using the client
+// and a failure reaches it on the first attempt.
+func NewClient(t testing.TB, transport http.RoundTripper) *elasticsearch.Client {
+	t.Helper()
+	client, err := elasticsearch.NewClient(elasticsearch.Config{Transport: transport, DisableRetry: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
@@ -0,0 +1,203 @@
+// Package logging provides the structured slog loggers used by the programs in this
+// module and a client side slow query log.
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Attribute keys shared by every log line about an Elasticsearch call
+const (
+	KeyOperation = "operation"
+	KeyIndex     = "index"
+	KeyTook      = "took"
+	KeyHits      = "hits"
+	KeyStatus    = "status"
+	KeyError     = "error"
+	KeyQuery     = "query"
+)
+
+// New returns a logger writing JSON lines to w, or text lines when text is true
+func New(w io.Writer, level slog.Level, text bool) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	if text {
+		return slog.New(slog.NewTextHandler(w, options))
+	}
+	return slog.New(slog.NewJSONHandler(w, options))
+}
+
+// Default returns a text logger on stderr at info level
+func Default() *slog.Logger {
+	return New(os.Stderr, slog.LevelInfo, true)
+}
+
+// OrDefault returns logger, or slog.Default() when it is nil
+func OrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
+
+// Redacted replaces the value of sensitive fields in logged query bodies
+const Redacted = "[REDACTED]"
+
+// DefaultSensitiveFields are redacted from slow query logs unless overridden
+var DefaultSensitiveFields = []string{
+	"email",
+	"password",
+	"api_key",
+	"apikey",
+	"token",
+	"secret",
+	"authorization",
+}
+
+// SlowLog logs the full body of requests that take longer than Threshold
+type SlowLog struct {
+	Logger          *slog.Logger
+	Threshold       time.Duration // Requests at or above this latency are logged; 0 disables the log
+	SensitiveFields []string      // Field names whose values are redacted, defaults to DefaultSensitiveFields
+}
+
+// Observe logs the request when it was slow. body is the JSON request body.
+func (s *SlowLog) Observe(
+	ctx context.Context,
+	operation, index string,
+	took time.Duration,
+	body []byte,
+) {
+	if s == nil || s.Threshold <= 0 || took < s.Threshold {
+		return
+	}
+
+	fields := s.SensitiveFields
+	if fields == nil {
+		fields = DefaultSensitiveFields
+	}
+	OrDefault(s.Logger).WarnContext(ctx, "slow query",
+		slog.String(KeyOperation, operation),
+		slog.String(KeyIndex, index),
+		slog.Duration(KeyTook, took),
+		slog.Duration("threshold", s.Threshold),
+		slog.String(KeyQuery, string(Redact(body, fields))),
+	)
+}
+
+// Redact replaces the values of the given fields anywhere in a JSON document. Field names
+// match case-insensitively and also match the last segment of dotted paths, so both
+// {"match": {"email": "a@b.c"}} and {"term": {"user.email": "a@b.c"}} are redacted.
+// Queries that search one text in a list of fields, like multi_match, have their query
+// redacted when one of the fields, or a wildcard pattern among them, names a given field,
+// or when they list no fields and so search all of them. A query_string is also redacted
+// when its text names a given field, as in "email:a@b.c".
+// Bodies that are not JSON are replaced entirely.
+func Redact(body []byte, fields []string) []byte {
+	if len(body) == 0 {
+		return body
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return []byte(Redacted)
+	}
+
+	sensitive := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		sensitive[strings.ToLower(field)] = true
+	}
+
+	redacted, err := json.Marshal(redactValue(doc, sensitive))
+	if err != nil {
+		return []byte(Redacted)
+	}
+	return redacted
+}
+
+func redactValue(value interface{}, sensitive map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if isSensitive(key, sensitive) {
+				v[key] = Redacted
+				continue
+			}
+			if query, ok := child.(map[string]interface{}); ok && slices.Contains(multiFieldQueries, key) && targetsSensitive(key, query, sensitive) {
+				query["query"] = Redacted
+			}
+			v[key] = redactValue(child, sensitive)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactValue(child, sensitive)
+		}
+		return v
+	}
+	return value
+}
+
+// multiFieldQueries search the text in their query parameter in the fields they list
+var multiFieldQueries = []string{"multi_match", "combined_fields", "query_string", "simple_query_string"}
+
+// queryStringField matches the fields named in the text of a query_string, like email in
+// "email:a@b.c" or user.email in "user.email:(a OR b)"
+var queryStringField = regexp.MustCompile(`([\w.*?\\]+):`)
+
+// targetsSensitive reports whether a multi field query of the given kind searches a sensitive
+// field: one named by its fields or default_field, or any field when it names none
+func targetsSensitive(kind string, query map[string]interface{}, sensitive map[string]bool) bool {
+	fields, _ := query["fields"].([]interface{})
+	if defaultField, ok := query["default_field"]; ok {
+		fields = append(fields, defaultField)
+	}
+	if len(fields) == 0 {
+		// index.query.default_field applies, which is every field unless the index sets it
+		return true
+	}
+	if kind == "query_string" {
+		text, _ := query["query"].(string)
+		for _, match := range queryStringField.FindAllStringSubmatch(text, -1) {
+			fields = append(fields, strings.ReplaceAll(match[1], `\`, ""))
+		}
+	}
+	for _, value := range fields {
+		field, _ := value.(string)
+		field, _, _ = strings.Cut(strings.ToLower(field), "^") // Boosts, e.g. "email^2"
+		if isSensitive(field, sensitive) {
+			return true
+		}
+		if !strings.Contains(field, "*") {
+			continue
+		}
+		// Patterns like "*", "e*" or "user.*" may expand to a sensitive field
+		for name := range sensitive {
+			for _, pattern := range []string{field, field[strings.LastIndex(field, ".")+1:]} {
+				if matched, _ := path.Match(pattern, name); matched {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func isSensitive(key string, sensitive map[string]bool) bool {
+	key = strings.ToLower(key)
+	if sensitive[key] {
+		return true
+	}
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return sensitive[key[i+1:]]
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			"match on a sensitive field",
+			`{"query": {"match": {"email": "a@b.c"}}}`,
+			`{"query": {"match": {"email": "[REDACTED]"}}}`,
+		},
+		{
+			"term on a dotted sensitive field",
+			`{"query": {"term": {"user.Email": "a@b.c"}}}`,
+			`{"query": {"term": {"user.Email": "[REDACTED]"}}}`,
+		},
+		{
+			"match on another field",
+			`{"query": {"match": {"name": "john"}}}`,
+			`{"query": {"match": {"name": "john"}}}`,
+		},
+		{
+			"multi_match including a sensitive field",
+			`{"query": {"multi_match": {"query": "a@b.c", "fields": ["name", "email"]}}}`,
+			`{"query": {"multi_match": {"query": "[REDACTED]", "fields": ["name", "email"]}}}`,
+		},
+		{
+			"multi_match with a boosted sensitive field",
+			`{"query": {"multi_match": {"query": "a@b.c", "fields": ["name^3", "user.email^2"]}}}`,
+			`{"query": {"multi_match": {"query": "[REDACTED]", "fields": ["name^3", "user.email^2"]}}}`,
+		},
+		{
+			"multi_match with a pattern matching a sensitive field",
+			`{"query": {"multi_match": {"query": "a@b.c", "fields": ["name", "e*"]}}}`,
+			`{"query": {"multi_match": {"query": "[REDACTED]", "fields": ["name", "e*"]}}}`,
+		},
+		{
+			"multi_match on other fields",
+			`{"query": {"multi_match": {"query": "gaming laptop", "fields": ["name", "description"]}}}`,
+			`{"query": {"multi_match": {"query": "gaming laptop", "fields": ["name", "description"]}}}`,
+		},
+		{
+			"query_string with a sensitive default field",
+			`{"query": {"query_string": {"query": "a@b.c", "default_field": "email"}}}`,
+			`{"query": {"query_string": {"query": "[REDACTED]", "default_field": "email"}}}`,
+		},
+		{
+			"query_string without fields",
+			`{"query": {"query_string": {"query": "a@b.c"}}}`,
+			`{"query": {"query_string": {"query": "[REDACTED]"}}}`,
+		},
+		{
+			"simple_query_string without fields",
+			`{"query": {"simple_query_string": {"query": "a@b.c"}}}`,
+			`{"query": {"simple_query_string": {"query": "[REDACTED]"}}}`,
+		},
+		{
+			"query_string naming a sensitive field in its text",
+			`{"query": {"query_string": {"query": "name:john AND email:john@example.com", "fields": ["name"]}}}`,
+			`{"query": {"query_string": {"query": "[REDACTED]", "fields": ["name"]}}}`,
+		},
+		{
+			"query_string naming a sensitive nested field in its text",
+			`{"query": {"query_string": {"query": "user.email:(a OR b)", "default_field": "name"}}}`,
+			`{"query": {"query_string": {"query": "[REDACTED]", "default_field": "name"}}}`,
+		},
+		{
+			"query_string naming other fields in its text",
+			`{"query": {"query_string": {"query": "name:john AND brand:acme", "default_field": "name"}}}`,
+			`{"query": {"query_string": {"query": "name:john AND brand:acme", "default_field": "name"}}}`,
+		},
+		{
+			"nested in a bool query",
+			`{"query": {"bool": {"should": [{"multi_match": {"query": "a@b.c", "fields": ["*"]}}, {"match": {"name": "john"}}]}}}`,
+			`{"query": {"bool": {"should": [{"multi_match": {"query": "[REDACTED]", "fields": ["*"]}}, {"match": {"name": "john"}}]}}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Redact([]byte(test.body), DefaultSensitiveFields)
+
+			var gotDoc, wantDoc interface{}
+			if err := json.Unmarshal(got, &gotDoc); err != nil {
+				t.Fatalf("Redact() returned invalid JSON %s: %v", got, err)
+			}
+			if err := json.Unmarshal([]byte(test.want), &wantDoc); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(gotDoc, wantDoc) {
+				t.Errorf("Redact() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRedactNotJSON(t *testing.T) {
+	if got := Redact([]byte("email=a@b.c"), DefaultSensitiveFields); string(got) != Redacted {
+		t.Errorf("Redact() = %s, want %s", got, Redacted)
+	}
+}
+
+func TestSlowLogObserve(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		took      time.Duration
+		wantLog   bool
+	}{
+		{"slow query", 100 * time.Millisecond, 150 * time.Millisecond, true},
+		{"at the threshold", 100 * time.Millisecond, 100 * time.Millisecond, true},
+		{"fast query", 100 * time.Millisecond, 50 * time.Millisecond, false},
+		{"disabled", 0, time.Hour, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			slowLog := &SlowLog{Logger: New(&out, slog.LevelInfo, false), Threshold: test.threshold}
+
+			slowLog.Observe(context.Background(), "search", "users", test.took, []byte(`{"query": {"match": {"email": "a@b.c"}}}`))
+
+			if (out.Len() > 0) != test.wantLog {
+				t.Fatalf("logged %q, want a line: %v", out.String(), test.wantLog)
+			}
+			if !test.wantLog {
+				return
+			}
+			var line map[string]interface{}
+			if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+				t.Fatal(err)
+			}
+			if line[KeyOperation] != "search" || line[KeyIndex] != "users" {
+				t.Errorf("line = %v, want the operation and index", line)
+			}
+			if query, _ := line[KeyQuery].(string); strings.Contains(query, "a@b.c") || !strings.Contains(query, Redacted) {
+				t.Errorf("%s = %q, want the email redacted", KeyQuery, query)
+			}
+		})
+	}
+}
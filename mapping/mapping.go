@@ -0,0 +1,283 @@
+// Package mapping builds Elasticsearch index mappings from the struct tags of Go documents,
+// so the mapping of an index and the type its documents decode into cannot drift apart.
+//
+// Field names come from the json tag. The es tag holds the field type followed by options:
+//
+//	Name  string    `json:"name" es:"text,analyzer=english,fields.keyword=keyword,fields.keyword.ignore_above=256"`
+//	Brand string    `json:"brand" es:"keyword,normalizer=lowercase_keyword"`
+//	Price float64   `json:"price" es:"float"`
+//	Tags  []string  `json:"tags"`  // keyword, inferred
+//	Seen  time.Time `json:"seen"`  // date, inferred
+//	Notes string    `json:"notes" es:"-"` // not mapped
+//
+// Without a type the field type is inferred from the Go type: strings are keywords, bools
+// booleans, integers longs, float32 floats, float64 doubles, time.Time dates and structs
+// objects. Slices and pointers map like their elements.
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Field is the mapping of a single document field
+type Field struct {
+	Type           string           `json:"type"`
+	Analyzer       string           `json:"analyzer,omitempty"`        // Analyzer used at index time (text fields)
+	SearchAnalyzer string           `json:"search_analyzer,omitempty"` // Analyzer used at query time, defaults to Analyzer
+	Normalizer     string           `json:"normalizer,omitempty"`      // Normalizer applied to keyword fields
+	IgnoreAbove    int              `json:"ignore_above,omitempty"`    // Keyword values longer than this are not indexed
+	Format         string           `json:"format,omitempty"`          // Date formats, defaults to strict_date_optional_time
+	Fields         map[string]Field `json:"fields,omitempty"`          // Multi-fields, e.g. name.keyword
+	Properties     map[string]Field `json:"properties,omitempty"`      // Fields of object and nested fields
+}
+
+// IndexSettings holds the index settings sent together with the mappings
+type IndexSettings struct {
+	NumberOfShards   int      `json:"number_of_shards,omitempty"`
+	NumberOfReplicas *int     `json:"number_of_replicas,omitempty"`
+	MaxNgramDiff     int      `json:"max_ngram_diff,omitempty"`
+	Analysis         Analysis `json:"analysis"`
+}
+
+// Analysis holds custom analysis components keyed by name.
+// Filters, tokenizers and char filters take arbitrary type specific options, so they stay untyped.
+type Analysis struct {
+	CharFilter map[string]interface{} `json:"char_filter,omitempty"`
+	Tokenizer  map[string]interface{} `json:"tokenizer,omitempty"`
+	Filter     map[string]interface{} `json:"filter,omitempty"`
+	Analyzer   map[string]Analyzer    `json:"analyzer,omitempty"`
+	Normalizer map[string]Normalizer  `json:"normalizer,omitempty"`
+}
+
+// Analyzer is a custom analyzer: char filters, then a tokenizer, then token filters in order
+type Analyzer struct {
+	Type       string   `json:"type"`
+	Tokenizer  string   `json:"tokenizer"`
+	CharFilter []string `json:"char_filter,omitempty"`
+	Filter     []string `json:"filter,omitempty"`
+}
+
+// Normalizer is an analyzer for keyword fields that produces a single token
+type Normalizer struct {
+	Type       string   `json:"type"`
+	CharFilter []string `json:"char_filter,omitempty"`
+	Filter     []string `json:"filter,omitempty"`
+}
+
+// Mapping is the mappings section of an index
+type Mapping struct {
+	// strict rejects documents with fields the mapping does not know, instead of guessing their type
+	Dynamic    string           `json:"dynamic,omitempty"`
+	Properties map[string]Field `json:"properties"`
+}
+
+// Index is the body of a create index request
+type Index struct {
+	Settings *IndexSettings `json:"settings,omitempty"`
+	Mappings Mapping        `json:"mappings"`
+}
+
+// For returns the strict mapping of documents of the type of v, a struct or pointer to struct
+func For(v interface{}) (Mapping, error) {
+	properties, err := Properties(reflect.TypeOf(v))
+	if err != nil {
+		return Mapping{}, err
+	}
+	return Mapping{Dynamic: "strict", Properties: properties}, nil
+}
+
+// MustFor is like For but panics on invalid tags. It is meant for package level variables.
+func MustFor(v interface{}) Mapping {
+	m, err := For(v)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Properties returns the mapping of every field of the struct type t that encoding/json encodes
+func Properties(t reflect.Type) (map[string]Field, error) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("mapping: %v is not a struct", t)
+	}
+
+	properties := map[string]Field{}
+	var errs []error
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, skip := jsonName(sf)
+		if skip || sf.Tag.Get("es") == "-" {
+			continue
+		}
+
+		// Fields of embedded structs are promoted, as encoding/json does
+		if sf.Anonymous && name == "" && indirect(sf.Type).Kind() == reflect.Struct {
+			embedded, err := Properties(sf.Type)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			for key, field := range embedded {
+				if _, ok := properties[key]; !ok {
+					properties[key] = field
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		field, err := fieldOf(sf.Type, sf.Tag.Get("es"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mapping: %s.%s: %w", t.Name(), sf.Name, err))
+			continue
+		}
+		properties[name] = field
+	}
+	return properties, errors.Join(errs...)
+}
+
+// jsonName returns the name encoding/json uses for sf, empty for untagged fields,
+// and whether encoding/json skips the field
+func jsonName(sf reflect.StructField) (string, bool) {
+	if !sf.IsExported() && !sf.Anonymous {
+		return "", true
+	}
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name, false
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return t // []byte encodes as a base64 string
+		}
+		t = t.Elem()
+	}
+	return t
+}
+
+// fieldOf returns the mapping of a field of Go type t with the es tag tag
+func fieldOf(t reflect.Type, tag string) (Field, error) {
+	t = indirect(t)
+	fieldType, options, _ := strings.Cut(tag, ",")
+
+	var field Field
+	switch {
+	case fieldType == "" || fieldType == "object" || fieldType == "nested":
+		inferred, err := infer(t)
+		if err != nil {
+			return Field{}, err
+		}
+		field = inferred
+		if fieldType == "nested" {
+			if field.Type != "object" {
+				return Field{}, fmt.Errorf("nested requires a struct, got %v", t)
+			}
+			field.Type = fieldType
+		}
+	default:
+		field.Type = fieldType
+	}
+
+	if options == "" {
+		return field, nil
+	}
+	for _, option := range strings.Split(options, ",") {
+		key, value, ok := strings.Cut(option, "=")
+		if !ok || value == "" {
+			return Field{}, fmt.Errorf("option %q must be key=value", option)
+		}
+		if sub, ok := strings.CutPrefix(key, "fields."); ok {
+			name, subKey, _ := strings.Cut(sub, ".")
+			if field.Fields == nil {
+				field.Fields = map[string]Field{}
+			}
+			multi := field.Fields[name]
+			if subKey == "" {
+				multi.Type = value
+			} else if err := multi.set(subKey, value); err != nil {
+				return Field{}, fmt.Errorf("multi-field %s: %w", name, err)
+			}
+			field.Fields[name] = multi
+			continue
+		}
+		if err := field.set(key, value); err != nil {
+			return Field{}, err
+		}
+	}
+	for name, multi := range field.Fields {
+		if multi.Type == "" {
+			return Field{}, fmt.Errorf("multi-field %s has no type", name)
+		}
+	}
+	return field, nil
+}
+
+// set applies one option of an es tag
+func (f *Field) set(key, value string) error {
+	switch key {
+	case "analyzer":
+		f.Analyzer = value
+	case "search_analyzer":
+		f.SearchAnalyzer = value
+	case "normalizer":
+		f.Normalizer = value
+	case "format":
+		f.Format = value
+	case "ignore_above":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("ignore_above must be a positive number, got %q", value)
+		}
+		f.IgnoreAbove = n
+	default:
+		return fmt.Errorf("unknown option %q", key)
+	}
+	return nil
+}
+
+// infer returns the default mapping of Go type t
+func infer(t reflect.Type) (Field, error) {
+	if t == timeType {
+		return Field{Type: "date"}, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return Field{Type: "keyword"}, nil
+	case reflect.Bool:
+		return Field{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Field{Type: "long"}, nil
+	case reflect.Uint64:
+		return Field{Type: "unsigned_long"}, nil
+	case reflect.Float32:
+		return Field{Type: "float"}, nil
+	case reflect.Float64:
+		return Field{Type: "double"}, nil
+	case reflect.Slice:
+		return Field{Type: "binary"}, nil // []byte, see indirect
+	case reflect.Struct:
+		properties, err := Properties(t)
+		if err != nil {
+			return Field{}, err
+		}
+		return Field{Type: "object", Properties: properties}, nil
+	}
+	return Field{}, fmt.Errorf("cannot infer a field type for %v, set one in the es tag", t)
+}
@@ -0,0 +1,86 @@
+package mapping
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City string `json:"city" es:"text"`
+}
+
+type audit struct {
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type document struct {
+	audit
+	ID       string    `json:"id"`
+	Title    string    `json:"title" es:"text,analyzer=english,fields.raw=keyword,fields.raw.ignore_above=256"`
+	Tags     []string  `json:"tags"`
+	Score    float32   `json:"score"`
+	Count    *int      `json:"count,omitempty"`
+	Address  address   `json:"address"`
+	Previous []address `json:"previous" es:"nested"`
+	Secret   string    `json:"-"`
+	Notes    string    `json:"notes" es:"-"`
+	internal string
+}
+
+func TestFor(t *testing.T) {
+	got, err := For(document{})
+	if err != nil {
+		t.Fatalf("For() error = %v", err)
+	}
+	city := map[string]Field{"city": {Type: "text"}}
+	want := Mapping{Dynamic: "strict", Properties: map[string]Field{
+		"updated_at": {Type: "date"},
+		"id":         {Type: "keyword"},
+		"title": {Type: "text", Analyzer: "english", Fields: map[string]Field{
+			"raw": {Type: "keyword", IgnoreAbove: 256},
+		}},
+		"tags":     {Type: "keyword"},
+		"score":    {Type: "float"},
+		"count":    {Type: "long"},
+		"address":  {Type: "object", Properties: city},
+		"previous": {Type: "nested", Properties: city},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("For() = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestForInvalidTags(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"unknown option", struct {
+			A string `es:"text,analyser=english"`
+		}{}},
+		{"option without value", struct {
+			A string `es:"text,analyzer"`
+		}{}},
+		{"bad ignore_above", struct {
+			A string `es:"keyword,ignore_above=-1"`
+		}{}},
+		{"multi-field without type", struct {
+			A string `es:"text,fields.raw.ignore_above=10"`
+		}{}},
+		{"nested scalar", struct {
+			A string `es:"nested"`
+		}{}},
+		{"no inferable type", struct {
+			A map[string]string
+		}{}},
+		{"not a struct", "document"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := For(test.v); err == nil {
+				t.Fatal("For() accepted invalid tags")
+			}
+		})
+	}
+}
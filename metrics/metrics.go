@@ -0,0 +1,155 @@
+// Package metrics records Prometheus metrics for Elasticsearch searches, CRUD and bulk operations.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Elastic-Search/breaker"
+	"Elastic-Search/eserrors"
+	"Elastic-Search/httpapi"
+	"Elastic-Search/logging"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the collectors of one registry. A nil *Metrics records nothing.
+type Metrics struct {
+	registry         *prometheus.Registry
+	requests         *prometheus.CounterVec
+	latency          *prometheus.HistogramVec
+	bulkItems        *prometheus.CounterVec
+	bulkItemFailures *prometheus.CounterVec
+	searchHits       *prometheus.HistogramVec
+}
+
+// Default is the registry shared by the programs in this module
+var Default = New()
+
+// New creates the collectors and registers them in a fresh registry
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "elasticsearch_client",
+			Name:      "requests_total",
+			Help:      "Elasticsearch requests by component, operation, index and response status.",
+		}, []string{"component", "operation", "index", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "elasticsearch_client",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of Elasticsearch operations including retries.",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"component", "operation", "index"}),
+		bulkItems: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "elasticsearch_client",
+			Name:      "bulk_items_total",
+			Help:      "Documents sent in bulk requests.",
+		}, []string{"index"}),
+		bulkItemFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "elasticsearch_client",
+			Name:      "bulk_item_failures_total",
+			Help:      "Bulk items rejected by Elasticsearch by error type.",
+		}, []string{"index", "error_type"}),
+		searchHits: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "elasticsearch_client",
+			Name:      "search_hits",
+			Help:      "Total hits reported by searches.",
+			Buckets:   []float64{0, 1, 10, 100, 1000, 10000, 100000},
+		}, []string{"query_kind", "index"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.latency,
+		m.bulkItems,
+		m.bulkItemFailures,
+		m.searchHits,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records the outcome and latency of an Elasticsearch operation.
+// Searches use the query kind (match, fuzzy, ...) as operation.
+func (m *Metrics) ObserveRequest(
+	component, operation, index string,
+	start time.Time,
+	res *esapi.Response,
+	err error,
+) {
+	if m == nil {
+		return
+	}
+	m.latency.WithLabelValues(component, operation, index).Observe(time.Since(start).Seconds())
+	m.requests.WithLabelValues(component, operation, index, status(res, err)).Inc()
+}
+
+// ObserveHits records the total hits of a search
+func (m *Metrics) ObserveHits(queryKind, index string, hits int64) {
+	if m == nil {
+		return
+	}
+	m.searchHits.WithLabelValues(queryKind, index).Observe(float64(hits))
+}
+
+// ObserveBulk records the documents of a bulk request and its failed items by error type
+func (m *Metrics) ObserveBulk(index string, items int, failures map[string]int) {
+	if m == nil {
+		return
+	}
+	m.bulkItems.WithLabelValues(index).Add(float64(items))
+	for errorType, count := range failures {
+		m.bulkItemFailures.WithLabelValues(index, errorType).Add(float64(count))
+	}
+}
+
+// status labels a request by HTTP status, or by the error that kept it from getting one
+func status(res *esapi.Response, err error) string {
+	var esErr *eserrors.ESError
+	var rejected *breaker.RejectedError
+	switch {
+	case errors.As(err, &esErr):
+		return strconv.Itoa(esErr.Status)
+	case errors.As(err, &rejected):
+		return "rejected"
+	case err != nil:
+		return "error"
+	case res != nil:
+		return strconv.Itoa(res.StatusCode)
+	}
+	return "unknown"
+}
+
+// Serve exposes the default registry on addr at /metrics until ctx is cancelled. It returns
+// once addr is bound, so a taken port fails the caller, and serves in the background.
+// A nil logger defaults to slog.Default().
+func Serve(ctx context.Context, addr string, logger *slog.Logger) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error serving metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Default.Handler())
+	api := &httpapi.API{Name: "metrics", Logger: logger}
+	go func() {
+		if err := api.Serve(ctx, listener, mux); err != nil {
+			logging.OrDefault(logger).Error("error serving metrics", "addr", addr, logging.KeyError, err)
+		}
+	}()
+	return nil
+}
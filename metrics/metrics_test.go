@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"Elastic-Search/breaker"
+	"Elastic-Search/eserrors"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+func TestHandler(t *testing.T) {
+	m := New()
+	start := time.Now()
+	m.ObserveRequest("search", "match", "products", start, &esapi.Response{StatusCode: http.StatusOK}, nil)
+	m.ObserveRequest("search", "match", "products", start, nil, &eserrors.ESError{Status: http.StatusTooManyRequests})
+	m.ObserveRequest("crud", "get", "users", start, nil, &breaker.RejectedError{Reason: breaker.ErrOpen})
+	m.ObserveHits("match", "products", 42)
+	m.ObserveBulk("products", 100, map[string]int{"mapper_parsing_exception": 2})
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("Content-Type = %q, want the Prometheus text format", contentType)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE elasticsearch_client_requests_total counter",
+		`elasticsearch_client_requests_total{component="search",index="products",operation="match",status="200"} 1`,
+		`elasticsearch_client_requests_total{component="search",index="products",operation="match",status="429"} 1`,
+		`elasticsearch_client_requests_total{component="crud",index="users",operation="get",status="rejected"} 1`,
+		"# TYPE elasticsearch_client_request_duration_seconds histogram",
+		`elasticsearch_client_request_duration_seconds_count{component="search",index="products",operation="match"} 2`,
+		`elasticsearch_client_bulk_items_total{index="products"} 100`,
+		`elasticsearch_client_bulk_item_failures_total{error_type="mapper_parsing_exception",index="products"} 2`,
+		"# TYPE elasticsearch_client_search_hits histogram",
+		`elasticsearch_client_search_hits_bucket{index="products",query_kind="match",le="100"} 1`,
+		`elasticsearch_client_search_hits_sum{index="products",query_kind="match"} 42`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		res  *esapi.Response
+		err  error
+		want string
+	}{
+		{"response", &esapi.Response{StatusCode: http.StatusCreated}, nil, "201"},
+		{"error response", nil, &eserrors.ESError{Status: http.StatusNotFound}, "404"},
+		{"rejected by the breaker", nil, &breaker.RejectedError{Reason: breaker.ErrTooManyInFlight}, "rejected"},
+		{"transport error", nil, errors.New("connection refused"), "error"},
+		{"nothing", nil, nil, "unknown"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := status(test.res, test.err); got != test.want {
+				t.Errorf("status() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	// Clients built without metrics pass a nil *Metrics around
+	m.ObserveRequest("search", "match", "products", time.Now(), nil, nil)
+	m.ObserveHits("match", "products", 1)
+	m.ObserveBulk("products", 1, nil)
+}
+
+func TestServe(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	if err := Serve(context.Background(), taken.Addr().String(), logger); err == nil {
+		t.Fatal("Serve() on a taken address succeeded")
+	}
+
+	// A free address is served until ctx is cancelled
+	addr := taken.Addr().String()
+	taken.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := Serve(ctx, addr, logger); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	res, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	// Cancelling ctx shuts the server down and frees the address
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		listener, err := net.Listen("tcp", addr)
+		if err == nil {
+			listener.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("metrics server still listening after cancel: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,178 @@
+// Package retry retries Elasticsearch requests that failed for transient reasons
+// using exponential backoff with jitter.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Policy configures how an operation is retried
+type Policy struct {
+	MaxAttempts       int           // Total attempts including the first one; 1 disables retries
+	InitialBackoff    time.Duration // Wait before the first retry
+	MaxBackoff        time.Duration // Upper bound for a single wait
+	Multiplier        float64       // Growth factor of the wait between attempts; below 1 the wait stays constant
+	Jitter            float64       // Fraction of each wait that is randomized, between 0 and 1
+	RetryableStatuses []int         // HTTP statuses considered transient
+}
+
+// DefaultPolicy retries 429, 502, 503 and 504 responses and connection failures up to four times
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:    4,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+		RetryableStatuses: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// NoRetry makes a single attempt
+func NoRetry() Policy {
+	return Policy{MaxAttempts: 1}
+}
+
+// Policies holds a default policy and per operation overrides keyed by operation name
+type Policies struct {
+	Default      Policy
+	PerOperation map[string]Policy
+}
+
+// DefaultPolicies uses DefaultPolicy for every operation
+func DefaultPolicies() Policies {
+	return Policies{Default: DefaultPolicy()}
+}
+
+// For returns the policy of an operation. Operations that are not idempotent are never
+// retried: the first attempt may have been applied even though its response was lost.
+func (p Policies) For(op string, idempotent bool) Policy {
+	if !idempotent {
+		return NoRetry()
+	}
+	if policy, ok := p.PerOperation[op]; ok {
+		return policy
+	}
+	return p.Default
+}
+
+// Backoff returns the wait before retry number attempt (starting at 1)
+func (p Policy) Backoff(attempt int) time.Duration {
+	// A zero Multiplier would otherwise turn every wait after the first one into none
+	multiplier := max(p.Multiplier, 1)
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff -= backoff * p.Jitter * rand.Float64()
+	}
+	return time.Duration(backoff)
+}
+
+// retryableStatus reports whether a response status is transient under this policy
+func (p Policy) retryableStatus(status int) bool {
+	return slices.Contains(p.RetryableStatuses, status)
+}
+
+// Do calls fn until it succeeds, fails permanently, runs out of attempts or ctx is done.
+// fn must build a fresh request body on every call. Responses of failed attempts are closed;
+// the last response is returned unread so callers handle it like a single attempt.
+func Do(
+	ctx context.Context,
+	policy Policy,
+	fn func(ctx context.Context) (*esapi.Response, error),
+) (*esapi.Response, error) {
+	attempts := max(policy.MaxAttempts, 1)
+
+	for attempt := 1; ; attempt++ {
+		res, err := fn(ctx)
+
+		retryable := false
+		switch {
+		case err != nil:
+			retryable = transientError(err)
+		case res != nil && policy.retryableStatus(res.StatusCode):
+			retryable = true
+		}
+		if !retryable || attempt >= attempts {
+			return res, err
+		}
+
+		wait := policy.Backoff(attempt)
+		if res != nil {
+			wait = max(wait, retryAfter(res))
+		}
+
+		// Give up early when the deadline would pass before the next attempt starts
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return res, err
+		}
+		if res != nil {
+			drainAndClose(res.Body)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("retry aborted after %d attempts: %w", attempt, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// transientError reports whether a transport error is worth retrying
+func transientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryAfter returns the wait requested by a Retry-After header in seconds, if any
+func retryAfter(res *esapi.Response) time.Duration {
+	if res.Header == nil {
+		return 0
+	}
+	seconds, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func drainAndClose(body io.ReadCloser) {
+	if body == nil {
+		return
+	}
+	// The body of a discarded attempt carries nothing the caller needs
+	_, _ = io.Copy(io.Discard, body)
+	_ = body.Close()
+}
@@ -0,0 +1,251 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	for attempt, want := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	} {
+		if got := policy.Backoff(attempt); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestBackoffConstantBelowMultiplierOne(t *testing.T) {
+	for _, multiplier := range []float64{0, 0.5, 1} {
+		policy := Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: multiplier}
+		for _, attempt := range []int{1, 2, 5} {
+			if got := policy.Backoff(attempt); got != 100*time.Millisecond {
+				t.Errorf("Multiplier %v: Backoff(%d) = %s, want 100ms", multiplier, attempt, got)
+			}
+		}
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	policy := Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2, Jitter: 0.5}
+	for attempt, full := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 6: time.Second} {
+		for range 100 {
+			// Jitter only shortens the wait, by at most half of it
+			if got := policy.Backoff(attempt); got < full/2 || got > full {
+				t.Fatalf("Backoff(%d) = %s, want between %s and %s", attempt, got, full/2, full)
+			}
+		}
+	}
+}
+
+func TestFor(t *testing.T) {
+	slow := Policy{MaxAttempts: 10}
+	policies := Policies{Default: DefaultPolicy(), PerOperation: map[string]Policy{"bulk index": slow}}
+
+	if got := policies.For("bulk index", true); got.MaxAttempts != slow.MaxAttempts {
+		t.Errorf("For(bulk index) = %+v, want the override", got)
+	}
+	if got := policies.For("search", true); got.MaxAttempts != DefaultPolicy().MaxAttempts {
+		t.Errorf("For(search) = %+v, want the default", got)
+	}
+	for _, op := range []string{"bulk index", "search"} {
+		if got := policies.For(op, false); got.MaxAttempts != 1 {
+			t.Errorf("For(%s, not idempotent) makes %d attempts, want 1", op, got.MaxAttempts)
+		}
+	}
+}
+
+func TestTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection reset", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, true},
+		{"connection refused", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, true},
+		{"broken pipe", fmt.Errorf("write: %w", syscall.EPIPE), true},
+		{"EOF", fmt.Errorf("reading response: %w", io.EOF), true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"network timeout", &net.DNSError{Err: "i/o timeout", IsTimeout: true}, true},
+		{"cancelled", fmt.Errorf("request: %w", context.Canceled), false},
+		{"deadline exceeded", context.DeadlineExceeded, false},
+		{"other error", errors.New("unsupported protocol scheme"), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := transientError(test.err); got != test.want {
+				t.Errorf("transientError(%v) = %v, want %v", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+// trackedBody records whether the body of a response was read to the end and closed
+type trackedBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func (b *trackedBody) drained() bool {
+	n, _ := b.Read(make([]byte, 1))
+	return n == 0
+}
+
+func response(status int, header http.Header) (*esapi.Response, *trackedBody) {
+	body := &trackedBody{Reader: strings.NewReader(`{"error": "busy"}`)}
+	return &esapi.Response{StatusCode: status, Header: header, Body: body}, body
+}
+
+func TestDo(t *testing.T) {
+	policy := Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 2, RetryableStatuses: []int{http.StatusServiceUnavailable}}
+	tests := []struct {
+		name         string
+		statuses     []int // Status of each attempt, 0 to fail with err instead
+		err          error // Transport error of the attempts without a status
+		wantAttempts int
+		wantStatus   int // 0 when Do returns the transport error
+	}{
+		{"success", []int{200}, nil, 1, 200},
+		{"transient status", []int{503, 503, 200}, nil, 3, 200},
+		{"out of attempts", []int{503, 503, 503, 200}, nil, 3, 503},
+		{"permanent status", []int{400, 200}, nil, 1, 400},
+		{"transient error", []int{0, 200}, io.ErrUnexpectedEOF, 2, 200},
+		{"permanent error", []int{0, 200}, errors.New("unsupported protocol scheme"), 1, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			var bodies []*trackedBody
+			res, err := Do(context.Background(), policy, func(context.Context) (*esapi.Response, error) {
+				status := test.statuses[attempts]
+				attempts++
+				if status == 0 {
+					return nil, test.err
+				}
+				res, body := response(status, nil)
+				bodies = append(bodies, body)
+				return res, nil
+			})
+			if attempts != test.wantAttempts {
+				t.Errorf("made %d attempts, want %d", attempts, test.wantAttempts)
+			}
+			status := 0
+			if res != nil {
+				status = res.StatusCode
+			}
+			if status != test.wantStatus || (status == 0) != (err != nil) {
+				t.Fatalf("Do() = status %d, error %v, want status %d", status, err, test.wantStatus)
+			}
+
+			// Discarded responses are drained and closed, the returned one is left to the caller
+			discarded := bodies
+			if res != nil {
+				discarded = bodies[:len(bodies)-1]
+				if last := bodies[len(bodies)-1]; last.closed || last.drained() {
+					t.Error("returned response was read or closed")
+				}
+			}
+			for i, body := range discarded {
+				if !body.closed || !body.drained() {
+					t.Errorf("discarded response %d was not drained and closed", i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestDoRetryAfter(t *testing.T) {
+	policy := Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, RetryableStatuses: []int{http.StatusTooManyRequests}}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	// The cluster asks to wait longer than the deadline allows, so there is no point in retrying
+	attempts := 0
+	res, err := Do(ctx, policy, func(context.Context) (*esapi.Response, error) {
+		attempts++
+		res, _ := response(http.StatusTooManyRequests, http.Header{"Retry-After": {"2"}})
+		return res, nil
+	})
+	if err != nil || res == nil || res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Do() = %v, %v, want the 429 response", res, err)
+	}
+	if attempts != 1 {
+		t.Errorf("made %d attempts, want 1", attempts)
+	}
+	if ctx.Err() != nil {
+		t.Error("Do() waited for the deadline instead of giving up")
+	}
+}
+
+func TestDoGivesUpBeforeDeadline(t *testing.T) {
+	policy := Policy{MaxAttempts: 3, InitialBackoff: time.Second, RetryableStatuses: []int{http.StatusServiceUnavailable}}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	start := time.Now()
+	res, err := Do(ctx, policy, func(context.Context) (*esapi.Response, error) {
+		attempts++
+		res, _ := response(http.StatusServiceUnavailable, nil)
+		return res, nil
+	})
+	if err != nil || res == nil || res.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Do() = %v, %v, want the 503 response", res, err)
+	}
+	if attempts != 1 || time.Since(start) >= 100*time.Millisecond {
+		t.Errorf("made %d attempts in %s, want 1 without waiting", attempts, time.Since(start))
+	}
+}
+
+func TestDoCancelled(t *testing.T) {
+	policy := Policy{MaxAttempts: 3, InitialBackoff: time.Hour, RetryableStatuses: []int{http.StatusServiceUnavailable}}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	res, err := Do(ctx, policy, func(context.Context) (*esapi.Response, error) {
+		cancel()
+		res, _ := response(http.StatusServiceUnavailable, nil)
+		return res, nil
+	})
+	if res != nil || !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() = %v, %v, want %v", res, err, context.Canceled)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		"-1":                            0,
+		"Wed, 21 Oct 2015 07:28:00 GMT": 0,
+	} {
+		res := &esapi.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+		if value != "" {
+			res.Header.Set("Retry-After", value)
+		}
+		if got := retryAfter(res); got != want {
+			t.Errorf("retryAfter(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const harnessBufferSize = 1 << 20
+
+// Harness runs a gRPC server on an in-memory listener so tests and demos can call the
+// services in process, with real serialization and deadlines but without a network port.
+type Harness struct {
+	Server *grpc.Server
+	Conn   *grpc.ClientConn
+
+	listener *bufconn.Listener
+}
+
+// NewHarness starts a server created by NewServer. register is called to add the
+// services before the server starts. Call Close to stop it.
+func NewHarness(logger *slog.Logger, register func(*grpc.Server)) (*Harness, error) {
+	listener := bufconn.Listen(harnessBufferSize)
+	server := NewServer(logger)
+	register(server)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		server.Stop()
+		return nil, fmt.Errorf("error dialing in-process server: %w", err)
+	}
+	return &Harness{Server: server, Conn: conn, listener: listener}, nil
+}
+
+// SearchClient returns a client of the search service
+func (h *Harness) SearchClient() SearchServiceClient {
+	return NewSearchServiceClient(h.Conn)
+}
+
+// UserClient returns a client of the user service
+func (h *Harness) UserClient() UserServiceClient {
+	return NewUserServiceClient(h.Conn)
+}
+
+// Close closes the client connection and stops the server
+func (h *Harness) Close() error {
+	err := h.Conn.Close()
+	h.Server.Stop()
+	return err
+}
@@ -0,0 +1,184 @@
+// Package rpc holds the gRPC definitions of the search and user services along
+// with the server setup and error mapping shared by their implementations.
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative search.proto users.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"Elastic-Search/breaker"
+	"Elastic-Search/eserrors"
+	"Elastic-Search/logging"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultTimeout bounds calls whose client did not set a deadline
+const DefaultTimeout = 30 * time.Second
+
+// NewServer creates a gRPC server whose handlers see the caller's deadline in their context,
+// or DefaultTimeout when the caller set none, so it reaches the Elasticsearch request.
+func NewServer(logger *slog.Logger, opts ...grpc.ServerOption) *grpc.Server {
+	logger = logging.OrDefault(logger)
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(func(
+			ctx context.Context,
+			req interface{},
+			info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler,
+		) (interface{}, error) {
+			ctx, cancel := withDefaultDeadline(ctx)
+			defer cancel()
+			res, err := handler(ctx, req)
+			logCall(ctx, logger, info.FullMethod, err)
+			return res, err
+		}),
+		grpc.ChainStreamInterceptor(func(
+			srv interface{},
+			stream grpc.ServerStream,
+			info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler,
+		) error {
+			ctx, cancel := withDefaultDeadline(stream.Context())
+			defer cancel()
+			err := handler(srv, &deadlineStream{ServerStream: stream, ctx: ctx})
+			logCall(ctx, logger, info.FullMethod, err)
+			return err
+		}),
+	)
+	return grpc.NewServer(opts...)
+}
+
+func withDefaultDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, DefaultTimeout)
+}
+
+// deadlineStream replaces the context of a server stream
+type deadlineStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *deadlineStream) Context() context.Context {
+	return s.ctx
+}
+
+func logCall(ctx context.Context, logger *slog.Logger, method string, err error) {
+	code := status.Code(err)
+	switch code {
+	case codes.OK:
+		logger.DebugContext(ctx, "rpc", "method", method)
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DeadlineExceeded:
+		logger.ErrorContext(ctx, "rpc failed", "method", method, "code", code.String(), logging.KeyError, err)
+	default:
+		logger.WarnContext(ctx, "rpc rejected", "method", method, "code", code.String(), logging.KeyError, err)
+	}
+}
+
+// Status converts an error of the Elasticsearch clients into a gRPC status error.
+// Errors that already carry a status are returned unchanged.
+func Status(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var esErr *eserrors.ESError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, eserrors.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, eserrors.ErrVersionConflict):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, eserrors.ErrBadRequest), errors.Is(err, eserrors.ErrMappingConflict):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, eserrors.ErrUnauthorized):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, eserrors.ErrTooManyRequests):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, breaker.ErrOpen),
+		errors.Is(err, breaker.ErrTooManyInFlight),
+		errors.Is(err, eserrors.ErrUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.As(err, &esErr):
+		return status.Error(codes.Internal, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// shutdownTimeout bounds the graceful stop of a server, like the shutdown of the HTTP servers.
+// Calls still running then, such as open streams, are cancelled.
+var shutdownTimeout = 10 * time.Second
+
+// ListenAndServe serves server on addr until ctx is cancelled, then stops it gracefully
+func ListenAndServe(ctx context.Context, server *grpc.Server, addr string, logger *slog.Logger) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %w", addr, err)
+	}
+	return Serve(ctx, server, listener, logger)
+}
+
+// Serve is ListenAndServe on a bound listener. The graceful stop waits for running calls
+// for at most 10 seconds before the server stops hard.
+func Serve(ctx context.Context, server *grpc.Server, listener net.Listener, logger *slog.Logger) error {
+	logger = logging.OrDefault(logger)
+	go func() {
+		<-ctx.Done()
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		timer := time.NewTimer(shutdownTimeout)
+		defer timer.Stop()
+		select {
+		case <-stopped:
+		case <-timer.C:
+			logger.Warn("gRPC calls still running after the shutdown timeout, stopping", "timeout", shutdownTimeout)
+			server.Stop()
+		}
+	}()
+
+	logger.Info("serving gRPC", "addr", listener.Addr().String())
+	return server.Serve(listener)
+}
+
+// ServeAll runs each server until all of them returned. When one fails, stop is called
+// so the others shut down too. It returns the first error.
+func ServeAll(stop func(), servers ...func() error) error {
+	errs := make(chan error, len(servers))
+	for _, serve := range servers {
+		go func() {
+			err := serve()
+			if err != nil {
+				stop()
+			}
+			errs <- err
+		}()
+	}
+
+	var first error
+	for range servers {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
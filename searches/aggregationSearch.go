@@ -1,4 +1,4 @@
-package main
+package searches
 
 import "context"
 
@@ -12,5 +12,5 @@ func (sc *SearchClient) AggregationSearch(
 		"aggs": aggs,
 	}
 
-	return sc.executeSearch(ctx, searchQuery)
+	return sc.executeSearch(ctx, "aggregation", SearchParams{}, searchQuery)
 }
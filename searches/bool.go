@@ -1,17 +1,20 @@
-package main
+package searches
 
 import "context"
 
 func (sc *SearchClient) BoolSearch(
 	ctx context.Context,
 	params map[string]interface{},
+	page SearchParams,
 ) (*SearchResult, error) {
 	// Boolean query with must, should, must_not
 	searchQuery := map[string]interface{}{
+		"from": page.From,
+		"size": page.Size,
 		"query": map[string]interface{}{
 			"bool": params,
 		},
 	}
 
-	return sc.executeSearch(ctx, searchQuery)
+	return sc.executeSearch(ctx, "bool", page, searchQuery)
 }
@@ -0,0 +1,278 @@
+package searches
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"Elastic-Search/breaker"
+	"Elastic-Search/catalog"
+	"Elastic-Search/eserrors"
+	"Elastic-Search/logging"
+	"Elastic-Search/metrics"
+	"Elastic-Search/retry"
+	"Elastic-Search/tracing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+type SearchParams struct {
+	From int // Starting offset
+	Size int // Number of results per page
+
+	pit         string        // Point in time searched instead of the index, see stream
+	searchAfter []interface{} // Sort values of the last hit of the previous page of pit
+}
+
+// Config holds the settings of a SearchClient
+type Config struct {
+	Index       string             // Index to search, defaults to "products"
+	PartialMode PartialResultsMode // How shard failures and timeouts are reported
+	Retry       *retry.Policies    // Optional: Retry policies, defaults to retry.DefaultPolicies()
+	Breaker     *breaker.Breaker   // Optional: Circuit breaker guarding the client's transport, reported by BreakerState
+	Metrics     *metrics.Metrics   // Optional: Prometheus metrics for every search
+	Logger      *slog.Logger       // Optional: Structured logger, defaults to slog.Default()
+	SlowQuery   time.Duration      // Optional: Log request bodies of searches slower than this
+	// Optional: Called with the body of every search before it is sent, e.g. to show or replay it
+	OnQuery func(kind, index string, body []byte)
+}
+
+// NewSearchClient creates a SearchClient running its searches through client
+func NewSearchClient(client *elasticsearch.Client, config Config) *SearchClient {
+	sc := &SearchClient{
+		client:      client,
+		index:       config.Index,
+		partialMode: config.PartialMode,
+		retry:       retry.DefaultPolicies(),
+		breaker:     config.Breaker,
+		metrics:     config.Metrics,
+		logger:      config.Logger,
+		slowLog:     &logging.SlowLog{Logger: config.Logger, Threshold: config.SlowQuery},
+		onQuery:     config.OnQuery,
+	}
+	if sc.index == "" {
+		sc.index = "products"
+	}
+	if config.Retry != nil {
+		sc.retry = *config.Retry
+	}
+	return sc
+}
+
+// Index returns the index the client searches
+func (sc *SearchClient) Index() string {
+	return sc.index
+}
+
+// WithIndex returns a copy of the client that searches index instead.
+// The copy shares the transport, breaker, metrics and logger of sc.
+func (sc *SearchClient) WithIndex(index string) *SearchClient {
+	clone := *sc
+	clone.index = index
+	return &clone
+}
+
+type SearchClient struct {
+	client      *elasticsearch.Client
+	index       string
+	partialMode PartialResultsMode
+	retry       retry.Policies
+	breaker     *breaker.Breaker
+	metrics     *metrics.Metrics
+	logger      *slog.Logger
+	slowLog     *logging.SlowLog
+	onQuery     func(kind, index string, body []byte)
+}
+
+// BreakerState returns the state of the circuit breaker guarding the client's transport
+func (sc *SearchClient) BreakerState() breaker.State {
+	if sc.breaker == nil {
+		return breaker.Closed
+	}
+	return sc.breaker.State()
+}
+
+// Product is the document type of the products index
+type Product = catalog.Product
+
+// SearchResult represents the search response structure
+type SearchResult struct {
+	Total    int64      `json:"total"`
+	Items    []Product  `json:"items"`
+	Aggs     any        `json:"aggregations,omitempty"`
+	TimedOut bool       `json:"timed_out"`
+	Shards   ShardStats `json:"shards"`
+	Warnings []string   `json:"warnings,omitempty"` // Set when partial results are returned in lenient mode
+
+	pitID    string        // Point in time to search for the next page, it may change between pages
+	lastSort []interface{} // Sort values of the last hit, the search_after of the next page
+}
+
+func (sc *SearchClient) executeSearch(
+	ctx context.Context,
+	kind string, // Query kind (match, fuzzy, ...) used to label metrics and spans
+	page SearchParams,
+	query map[string]interface{},
+) (_ *SearchResult, err error) {
+	ctx, span := tracing.Start(ctx, "search", sc.index, kind)
+	defer func() { tracing.End(span, err) }()
+
+	if page.pit != "" {
+		query["pit"] = map[string]interface{}{"id": page.pit, "keep_alive": pitKeepAlive}
+		// Hits of equal score are ordered by their place in the point in time
+		query["sort"] = []interface{}{"_score", "_shard_doc"}
+		if page.searchAfter != nil {
+			query["search_after"] = page.searchAfter
+			delete(query, "from")
+		}
+	}
+
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling query: %w", err)
+	}
+	if sc.onQuery != nil {
+		sc.onQuery(kind, sc.index, body)
+	}
+
+	result, err := sc.search(ctx, kind, body, page.pit != "")
+	if err != nil {
+		return nil, err
+	}
+
+	searchResult := &SearchResult{Total: totalHits(result)}
+	searchResult.pitID, _ = result["pit_id"].(string)
+
+	// Extract items
+	if hits, ok := result["hits"].(map[string]interface{}); ok {
+		if hitsList, ok := hits["hits"].([]interface{}); ok {
+			for _, hit := range hitsList {
+				hitMap := hit.(map[string]interface{})
+				source := hitMap["_source"].(map[string]interface{})
+
+				var product Product
+				sourceBytes, _ := json.Marshal(source)
+				err := json.Unmarshal(sourceBytes, &product)
+				if err != nil {
+					return nil, err
+				}
+				searchResult.Items = append(searchResult.Items, product)
+				searchResult.lastSort, _ = hitMap["sort"].([]interface{})
+			}
+		}
+	}
+
+	// Extract aggregations if present
+	if aggs, ok := result["aggregations"].(map[string]interface{}); ok {
+		searchResult.Aggs = aggs
+	}
+
+	// Extract shard statistics so partial results are not mistaken for complete ones
+	if timedOut, ok := result["timed_out"].(bool); ok {
+		searchResult.TimedOut = timedOut
+	}
+	if shards, ok := result["_shards"]; ok {
+		shardBytes, _ := json.Marshal(shards)
+		if err := json.Unmarshal(shardBytes, &searchResult.Shards); err != nil {
+			return nil, fmt.Errorf("error parsing shard statistics: %w", err)
+		}
+	}
+
+	sc.metrics.ObserveHits(kind, sc.index, searchResult.Total)
+	return sc.checkPartial(searchResult)
+}
+
+// search sends body to the _search endpoint of the index and decodes the raw response.
+// A body searching a point in time names no index, the point in time does.
+// It retries, records metrics and logs the request; callers own the span.
+func (sc *SearchClient) search(ctx context.Context, kind string, body []byte, pit bool) (_ map[string]interface{}, err error) {
+	start := time.Now()
+	var status int
+	var hits int64
+	defer func() {
+		took := time.Since(start)
+		sc.logSearch(ctx, kind, took, status, hits, err)
+		sc.slowLog.Observe(ctx, kind, sc.index, took, body)
+	}()
+
+	// Searches are read only, so they are always safe to retry
+	res, err := retry.Do(ctx, sc.retry.For("search", true), func(ctx context.Context) (*esapi.Response, error) {
+		options := []func(*esapi.SearchRequest){
+			sc.client.Search.WithContext(ctx),
+			sc.client.Search.WithBody(bytes.NewReader(body)),
+			// In strict mode the cluster fails the request instead of dropping shards
+			sc.client.Search.WithAllowPartialSearchResults(sc.partialMode != PartialResultsStrict),
+		}
+		if !pit {
+			options = append(options, sc.client.Search.WithIndex(sc.index))
+		}
+		return sc.client.Search(options...)
+	})
+	sc.metrics.ObserveRequest("search", kind, sc.index, start, res, err)
+	if err != nil {
+		return nil, fmt.Errorf("error executing search: %w", err)
+	}
+	status = res.StatusCode
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			sc.log().WarnContext(ctx, "error closing body", logging.KeyError, err)
+		}
+	}(res.Body)
+
+	if err := eserrors.FromResponse("search", res); err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+	hits = totalHits(result)
+	return result, nil
+}
+
+// totalHits extracts hits.total.value from a raw search response
+func totalHits(result map[string]interface{}) int64 {
+	if hits, ok := result["hits"].(map[string]interface{}); ok {
+		if total, ok := hits["total"].(map[string]interface{}); ok {
+			if value, ok := total["value"].(float64); ok {
+				return int64(value)
+			}
+		}
+	}
+	return 0
+}
+
+func (sc *SearchClient) log() *slog.Logger {
+	return logging.OrDefault(sc.logger)
+}
+
+// logSearch writes one line per search with the fields shared by all Elasticsearch logs
+func (sc *SearchClient) logSearch(
+	ctx context.Context,
+	kind string,
+	took time.Duration,
+	status int,
+	hits int64,
+	err error,
+) {
+	attrs := []slog.Attr{
+		slog.String(logging.KeyOperation, kind),
+		slog.String(logging.KeyIndex, sc.index),
+		slog.Duration(logging.KeyTook, took),
+		slog.Int(logging.KeyStatus, status),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any(logging.KeyError, err))
+		sc.log().LogAttrs(ctx, slog.LevelError, "search failed", attrs...)
+		return
+	}
+	attrs = append(attrs, slog.Int64(logging.KeyHits, hits))
+	sc.log().LogAttrs(ctx, slog.LevelDebug, "search", attrs...)
+}
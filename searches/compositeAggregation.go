@@ -0,0 +1,155 @@
+package searches
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+)
+
+// CompositeSource is one dimension of a composite aggregation, e.g. brand or category
+type CompositeSource struct {
+	Name          string // Key name used in the bucket key
+	Field         string // Field to build terms from
+	Order         string // Optional: "asc" or "desc"
+	MissingBucket bool   // Include documents without a value for Field
+}
+
+// CompositeBucket is a single bucket returned by a composite aggregation
+type CompositeBucket struct {
+	Key      map[string]interface{} `json:"key"`
+	DocCount int64                  `json:"doc_count"`
+}
+
+// KeyString returns the value of the named source in the bucket key as a string
+func (b CompositeBucket) KeyString(name string) string {
+	if v, ok := b.Key[name]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+type compositeAggResult struct {
+	AfterKey map[string]interface{} `json:"after_key"`
+	Buckets  []CompositeBucket      `json:"buckets"`
+}
+
+const compositeAggName = "composite_buckets"
+
+// CompositeAggregation iterates over every bucket of a composite aggregation.
+// Unlike terms aggregations it does not drop buckets beyond size: pages are
+// requested with after_key until Elasticsearch has no more buckets to return.
+func (sc *SearchClient) CompositeAggregation(
+	ctx context.Context,
+	sources []CompositeSource,
+	pageSize int,
+) iter.Seq2[CompositeBucket, error] {
+	return func(yield func(CompositeBucket, error) bool) {
+		if len(sources) == 0 {
+			yield(CompositeBucket{}, fmt.Errorf("composite aggregation needs at least one source"))
+			return
+		}
+		if pageSize <= 0 {
+			pageSize = 100
+		}
+
+		var afterKey map[string]interface{}
+		for {
+			page, err := sc.compositePage(ctx, sources, pageSize, afterKey)
+			if err != nil {
+				yield(CompositeBucket{}, err)
+				return
+			}
+
+			for _, bucket := range page.Buckets {
+				if !yield(bucket, nil) {
+					return
+				}
+			}
+
+			// Elasticsearch signals the last page by omitting after_key or returning no buckets
+			if len(page.Buckets) == 0 || page.AfterKey == nil {
+				return
+			}
+			afterKey = page.AfterKey
+		}
+	}
+}
+
+// CompositeBuckets collects every bucket of a composite aggregation into a slice
+func (sc *SearchClient) CompositeBuckets(
+	ctx context.Context,
+	sources []CompositeSource,
+	pageSize int,
+) ([]CompositeBucket, error) {
+	var buckets []CompositeBucket
+	for bucket, err := range sc.CompositeAggregation(ctx, sources, pageSize) {
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, nil
+}
+
+func (sc *SearchClient) compositePage(
+	ctx context.Context,
+	sources []CompositeSource,
+	pageSize int,
+	afterKey map[string]interface{},
+) (*compositeAggResult, error) {
+	compositeSources := make([]map[string]interface{}, 0, len(sources))
+	for _, source := range sources {
+		terms := map[string]interface{}{
+			"field": source.Field,
+		}
+		if source.Order != "" {
+			terms["order"] = source.Order
+		}
+		if source.MissingBucket {
+			terms["missing_bucket"] = true
+		}
+		compositeSources = append(compositeSources, map[string]interface{}{
+			source.Name: map[string]interface{}{
+				"terms": terms,
+			},
+		})
+	}
+
+	composite := map[string]interface{}{
+		"size":    pageSize,
+		"sources": compositeSources,
+	}
+	if afterKey != nil {
+		composite["after"] = afterKey
+	}
+
+	searchQuery := map[string]interface{}{
+		"size": 0,
+		"aggs": map[string]interface{}{
+			compositeAggName: map[string]interface{}{
+				"composite": composite,
+			},
+		},
+	}
+
+	result, err := sc.executeSearch(ctx, "composite", SearchParams{}, searchQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	aggs, ok := result.Aggs.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("composite aggregation missing from response")
+	}
+	aggBytes, err := json.Marshal(aggs[compositeAggName])
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling composite aggregation: %w", err)
+	}
+
+	var page compositeAggResult
+	if err := json.Unmarshal(aggBytes, &page); err != nil {
+		return nil, fmt.Errorf("error parsing composite aggregation: %w", err)
+	}
+	return &page, nil
+}
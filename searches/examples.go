@@ -0,0 +1,182 @@
+package searches
+
+import (
+	"context"
+	"log/slog"
+
+	"Elastic-Search/logging"
+)
+
+// RunExamples runs one search of every kind against the products index and logs the results
+func RunExamples(ctx context.Context, sc *SearchClient, logger *slog.Logger) {
+	logger = logging.OrDefault(logger)
+
+	searchParams := SearchParams{
+		From: 0,
+		Size: 5,
+	}
+
+	// Example 1: Simple Match Search
+	result, err := sc.MatchSearch(ctx, "name", "laptop", searchParams)
+	if err != nil {
+		logger.Error("Match search error", logging.KeyError, err)
+	}
+	logger.Info("match search result", "result", result)
+
+	// Example 2: Multi-Match Search
+	fields := []string{"name", "description"}
+	result, err = sc.MultiMatchSearch(ctx, "gaming laptop", fields, searchParams)
+	if err != nil {
+		logger.Error("Multi-match search error", logging.KeyError, err)
+	}
+	logger.Info("Multi-match search result", "result", result)
+
+	// Example 3: Boolean Search[Find Apple products with price >= 1000]
+	boolParams := map[string]interface{}{
+		"must": []map[string]interface{}{
+			{
+				"match": map[string]interface{}{
+					"brand": "Apple",
+				},
+			},
+		},
+		"filter": []map[string]interface{}{
+			{
+				"range": map[string]interface{}{
+					"price": map[string]interface{}{
+						"gte": 1000,
+					},
+				},
+			},
+		},
+	}
+	result, err = sc.BoolSearch(ctx, boolParams, searchParams)
+	if err != nil {
+		logger.Error("Bool search error", logging.KeyError, err)
+	}
+	logger.Info("Boolean search result", "result", result)
+
+	// Example 4: Range Search [Find products between $1000-$2000]
+	ranges := map[string]interface{}{
+		"gte": 1000,
+		"lte": 2000,
+	}
+	result, err = sc.RangeSearch(ctx, "price", ranges, searchParams)
+	if err != nil {
+		logger.Error("Range search error", logging.KeyError, err)
+	}
+	logger.Info("Range search result", "result", result)
+
+	// Example 5: Fuzzy Search
+	result, err = sc.FuzzySearch(ctx, "name", "lapto", 1, searchParams)
+	if err != nil {
+		logger.Error("Fuzzy search error", logging.KeyError, err)
+	}
+	logger.Info("Fuzzy search result", "result", result)
+
+	// Example 6: Aggregation Search
+	aggs := map[string]interface{}{
+		"avg_price": map[string]interface{}{
+			"avg": map[string]interface{}{
+				"field": "price",
+			},
+		},
+		"categories": map[string]interface{}{
+			"terms": map[string]interface{}{
+				"field": "categories",
+			},
+		},
+	}
+	result, err = sc.AggregationSearch(ctx, aggs)
+	if err != nil {
+		logger.Error("Aggregation search error", logging.KeyError, err)
+	}
+	logger.Info("Aggregation search result", "result", result)
+
+	// Example 7: Phrase Search
+	result, err = sc.PhraseSearch(ctx, "description", "gaming laptop", 1, searchParams)
+	if err != nil {
+		logger.Error("Phrase search error", logging.KeyError, err)
+	}
+	logger.Info("Phrase search result", "result", result)
+
+	// Example 8: Composite Aggregation [Every brand x category bucket]
+	sources := []CompositeSource{
+		{Name: "brand", Field: "brand"},
+		{Name: "category", Field: "categories"},
+	}
+	for bucket, err := range sc.CompositeAggregation(ctx, sources, 100) {
+		if err != nil {
+			logger.Error("Composite aggregation error", logging.KeyError, err)
+			break
+		}
+		logger.Info("composite bucket",
+			"brand", bucket.KeyString("brand"),
+			"category", bucket.KeyString("category"),
+			"doc_count", bucket.DocCount,
+		)
+	}
+
+	// Example 9: Pipeline Aggregations [Monthly price trend and top brands by price]
+	pipelineAggs := map[string]interface{}{
+		"per_month": DateHistogramAgg("created_at", "month", map[string]interface{}{
+			"avg_price":     MetricAgg("avg", "price"),
+			"avg_price_3m":  MovingAverage("avg_price", 3),
+			"product_delta": Derivative("_count"),
+		}),
+		"top_brands": TermsAgg("brand", 20, map[string]interface{}{
+			"avg_price": MetricAgg("avg", "price"),
+			"top_5":     BucketSort("avg_price", "desc", 5),
+		}),
+		"priciest_month": SiblingPipeline("max_bucket", "per_month>avg_price"),
+	}
+	result, err = sc.AggregationSearch(ctx, pipelineAggs)
+	if err != nil {
+		logger.Error("Pipeline aggregation error", logging.KeyError, err)
+	} else {
+		months, err := result.AggBuckets("per_month")
+		if err != nil {
+			logger.Error("Pipeline aggregation error", logging.KeyError, err)
+		}
+		for _, month := range months {
+			avg, _ := month.Value("avg_price")
+			movingAvg, _ := month.Value("avg_price_3m")
+			logger.Info("monthly price", "month", month.KeyAsString, "avg_price", avg, "avg_price_3m", movingAvg)
+		}
+	}
+
+	// Example 10: Metric Aggregations [Price distribution, distinct brands, best rated per brand]
+	metricAggs := map[string]interface{}{
+		"price_percentiles": PercentilesAgg("price", []float64{50, 90, 99}, 200),
+		"price_ranks":       PercentileRanksAgg("price", []float64{500, 1000}, 0),
+		"distinct_brands":   CardinalityAgg("brand", 1000),
+		"price_stats":       ExtendedStatsAgg("price", 0),
+		"brands": TermsAgg("brand", 20, map[string]interface{}{
+			"best_rated": TopHitsAgg(1, "rating", "desc"),
+		}),
+	}
+	result, err = sc.AggregationSearch(ctx, metricAggs)
+	if err != nil {
+		logger.Error("Metric aggregation error", logging.KeyError, err)
+	} else {
+		if brandCount, err := result.Cardinality("distinct_brands"); err == nil {
+			logger.Info("distinct brands", "count", brandCount)
+		}
+		if percentiles, err := result.Percentiles("price_percentiles"); err == nil {
+			for _, p := range percentiles {
+				if p.Value != nil {
+					logger.Info("price percentile", "percentile", p.Key, "price", *p.Value)
+				}
+			}
+		}
+		brands, err := result.AggBuckets("brands")
+		if err != nil {
+			logger.Error("Metric aggregation error", logging.KeyError, err)
+		}
+		for _, brand := range brands {
+			if best, err := brand.TopHits("best_rated"); err == nil && len(best) > 0 {
+				logger.Info("best rated", "brand", brand.Key, "name", best[0].Name, "rating", best[0].Rating)
+			}
+		}
+	}
+}
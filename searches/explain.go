@@ -0,0 +1,105 @@
+package searches
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"Elastic-Search/tracing"
+)
+
+// Explanation is a node of the score explanation of a hit
+type Explanation struct {
+	Value       float64       `json:"value"`
+	Description string        `json:"description"`
+	Details     []Explanation `json:"details,omitempty"`
+}
+
+// HitExplanation explains the score of one hit
+type HitExplanation struct {
+	ID          string      `json:"_id"`
+	Score       float64     `json:"_score"`
+	Explanation Explanation `json:"_explanation"`
+}
+
+// ProfileNode is a timed query, collector or aggregation in a search profile
+type ProfileNode struct {
+	Type        string        `json:"type,omitempty"`
+	Name        string        `json:"name,omitempty"` // Set for collectors
+	Description string        `json:"description,omitempty"`
+	Reason      string        `json:"reason,omitempty"` // Set for collectors
+	TimeInNanos int64         `json:"time_in_nanos"`
+	Children    []ProfileNode `json:"children,omitempty"`
+}
+
+// ShardProfile is the profile of a search on one shard
+type ShardProfile struct {
+	ID       string `json:"id"`
+	Searches []struct {
+		Query       []ProfileNode `json:"query"`
+		RewriteTime int64         `json:"rewrite_time"`
+		Collector   []ProfileNode `json:"collector"`
+	} `json:"searches"`
+	Aggregations []ProfileNode `json:"aggregations"`
+}
+
+// Explain runs the search request body again and returns why each hit got its score.
+// body is a request body as passed to Config.OnQuery.
+func (sc *SearchClient) Explain(ctx context.Context, body []byte) ([]HitExplanation, error) {
+	result, err := sc.replay(ctx, "explain", body)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Hits struct {
+			Hits []HitExplanation `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := remarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("error parsing explanations: %w", err)
+	}
+	return response.Hits.Hits, nil
+}
+
+// Profile runs the search request body again with profiling and returns the timings per shard
+func (sc *SearchClient) Profile(ctx context.Context, body []byte) ([]ShardProfile, error) {
+	result, err := sc.replay(ctx, "profile", body)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Profile struct {
+			Shards []ShardProfile `json:"shards"`
+		} `json:"profile"`
+	}
+	if err := remarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("error parsing profile: %w", err)
+	}
+	return response.Profile.Shards, nil
+}
+
+// replay sends body with the debug flag (explain or profile) switched on
+func (sc *SearchClient) replay(ctx context.Context, flag string, body []byte) (_ map[string]interface{}, err error) {
+	ctx, span := tracing.Start(ctx, "search", sc.index, flag)
+	defer func() { tracing.End(span, err) }()
+
+	var query map[string]interface{}
+	if err := json.Unmarshal(body, &query); err != nil {
+		return nil, fmt.Errorf("error parsing query: %w", err)
+	}
+	query[flag] = true
+	body, err = json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling query: %w", err)
+	}
+	return sc.search(ctx, flag, body, query["pit"] != nil)
+}
+
+// remarshal converts a decoded JSON value into v
+func remarshal(from interface{}, v interface{}) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
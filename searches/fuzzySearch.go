@@ -1,4 +1,4 @@
-package main
+package searches
 
 import "context"
 
@@ -6,10 +6,13 @@ func (sc *SearchClient) FuzzySearch(
 	ctx context.Context,
 	field, query string,
 	fuzziness interface{},
+	params SearchParams,
 ) (*SearchResult, error) {
 	// Fuzzy search for typo-tolerant searching. Match "laptop" even if typed as "latop". Uses Levenshtein distance
 	// The Levenshtein distance (also known as edit distance) is a metric used to measure the difference between two strings. It calculates the minimum number of single-character operations required to transform one string into the other.
 	searchQuery := map[string]interface{}{
+		"from": params.From,
+		"size": params.Size,
 		"query": map[string]interface{}{
 			"fuzzy": map[string]interface{}{
 				field: map[string]interface{}{
@@ -20,5 +23,5 @@ func (sc *SearchClient) FuzzySearch(
 		},
 	}
 
-	return sc.executeSearch(ctx, searchQuery)
+	return sc.executeSearch(ctx, "fuzzy", params, searchQuery)
 }
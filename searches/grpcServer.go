@@ -0,0 +1,255 @@
+package searches
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"Elastic-Search/httpapi"
+	"Elastic-Search/rpc"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Batches of streamed hits default to a full page of the REST API and are capped
+const maxBatchSize = 1000
+
+// searchService implements rpc.SearchServiceServer on top of a SearchClient
+type searchService struct {
+	rpc.UnimplementedSearchServiceServer
+	sc *SearchClient
+}
+
+// NewSearchService returns the gRPC search service backed by sc
+func NewSearchService(sc *SearchClient) rpc.SearchServiceServer {
+	return &searchService{sc: sc}
+}
+
+func (s *searchService) Match(req *rpc.MatchRequest, stream rpc.SearchService_MatchServer) error {
+	if err := requireField(req.GetField(), textFields); err != nil {
+		return grpcStatus(err)
+	}
+	if err := requireParam("query", req.GetQuery()); err != nil {
+		return grpcStatus(err)
+	}
+	return s.stream(stream.Context(), req.GetPage(), stream.Send, func(ctx context.Context, page SearchParams) (*SearchResult, error) {
+		return s.sc.MatchSearch(ctx, req.GetField(), req.GetQuery(), page)
+	})
+}
+
+func (s *searchService) MultiMatch(req *rpc.MultiMatchRequest, stream rpc.SearchService_MultiMatchServer) error {
+	if err := requireParam("query", req.GetQuery()); err != nil {
+		return grpcStatus(err)
+	}
+	fields := req.GetFields()
+	if len(fields) == 0 {
+		fields = textFields
+	}
+	for _, field := range fields {
+		if err := requireField(field, textFields); err != nil {
+			return grpcStatus(err)
+		}
+	}
+	return s.stream(stream.Context(), req.GetPage(), stream.Send, func(ctx context.Context, page SearchParams) (*SearchResult, error) {
+		return s.sc.MultiMatchSearch(ctx, req.GetQuery(), fields, page)
+	})
+}
+
+func (s *searchService) Bool(req *rpc.BoolRequest, stream rpc.SearchService_BoolServer) error {
+	clauses := req.GetClauses().AsMap()
+	if err := validateBoolClauses(clauses); err != nil {
+		return grpcStatus(err)
+	}
+	return s.stream(stream.Context(), req.GetPage(), stream.Send, func(ctx context.Context, page SearchParams) (*SearchResult, error) {
+		return s.sc.BoolSearch(ctx, clauses, page)
+	})
+}
+
+func (s *searchService) Range(req *rpc.RangeRequest, stream rpc.SearchService_RangeServer) error {
+	if err := requireField(req.GetField(), rangeFields); err != nil {
+		return grpcStatus(err)
+	}
+	ranges := map[string]interface{}{}
+	for op, bound := range map[string]*structpb.Value{
+		"gt":  req.GetGt(),
+		"gte": req.GetGte(),
+		"lt":  req.GetLt(),
+		"lte": req.GetLte(),
+	} {
+		if bound != nil {
+			ranges[op] = bound.AsInterface()
+		}
+	}
+	if len(ranges) == 0 {
+		return grpcStatus(httpapi.Invalid("range search needs at least one of gt, gte, lt, lte"))
+	}
+	return s.stream(stream.Context(), req.GetPage(), stream.Send, func(ctx context.Context, page SearchParams) (*SearchResult, error) {
+		return s.sc.RangeSearch(ctx, req.GetField(), ranges, page)
+	})
+}
+
+func (s *searchService) Fuzzy(req *rpc.FuzzyRequest, stream rpc.SearchService_FuzzyServer) error {
+	if err := requireField(req.GetField(), textFields); err != nil {
+		return grpcStatus(err)
+	}
+	if err := requireParam("query", req.GetQuery()); err != nil {
+		return grpcStatus(err)
+	}
+	var fuzziness interface{} = "AUTO"
+	if req.GetFuzziness() != "" && req.GetFuzziness() != "AUTO" {
+		distance, err := strconv.Atoi(req.GetFuzziness())
+		if err != nil || distance < 0 || distance > 2 {
+			return grpcStatus(httpapi.Invalid("fuzziness must be AUTO, 0, 1 or 2"))
+		}
+		fuzziness = distance
+	}
+	return s.stream(stream.Context(), req.GetPage(), stream.Send, func(ctx context.Context, page SearchParams) (*SearchResult, error) {
+		return s.sc.FuzzySearch(ctx, req.GetField(), req.GetQuery(), fuzziness, page)
+	})
+}
+
+func (s *searchService) Phrase(req *rpc.PhraseRequest, stream rpc.SearchService_PhraseServer) error {
+	if err := requireField(req.GetField(), textFields); err != nil {
+		return grpcStatus(err)
+	}
+	if err := requireParam("query", req.GetQuery()); err != nil {
+		return grpcStatus(err)
+	}
+	if req.GetSlop() < 0 || req.GetSlop() > 10 {
+		return grpcStatus(httpapi.Invalid("slop must be between 0 and 10"))
+	}
+	return s.stream(stream.Context(), req.GetPage(), stream.Send, func(ctx context.Context, page SearchParams) (*SearchResult, error) {
+		return s.sc.PhraseSearch(ctx, req.GetField(), req.GetQuery(), int(req.GetSlop()), page)
+	})
+}
+
+func (s *searchService) Aggregate(ctx context.Context, req *rpc.AggregateRequest) (*rpc.AggregateResponse, error) {
+	aggs := req.GetAggs().AsMap()
+	if len(aggs) == 0 {
+		return nil, grpcStatus(httpapi.Invalid("aggs must contain at least one aggregation"))
+	}
+
+	result, err := s.sc.AggregationSearch(ctx, aggs)
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+
+	aggregations, _ := result.Aggs.(map[string]interface{})
+	aggStruct, err := structpb.NewStruct(aggregations)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error converting aggregations: %v", err)
+	}
+	return &rpc.AggregateResponse{
+		Total:        result.Total,
+		Aggregations: aggStruct,
+		TimedOut:     result.TimedOut,
+		Warnings:     result.Warnings,
+	}, nil
+}
+
+// stream runs search page by page and sends each batch of hits until the requested
+// number of hits was sent or the results are exhausted. The pages are read from a point
+// in time with search_after, so only from is bounded by the result window, not size.
+func (s *searchService) stream(
+	ctx context.Context,
+	page *rpc.Page,
+	send func(*rpc.SearchResponse) error,
+	search func(ctx context.Context, page SearchParams) (*SearchResult, error),
+) error {
+	from, size, batchSize, err := streamWindow(page)
+	if err != nil {
+		return grpcStatus(err)
+	}
+
+	pit, err := s.sc.openPIT(ctx)
+	if err != nil {
+		return grpcStatus(err)
+	}
+	defer func() { s.sc.closePIT(ctx, pit) }()
+
+	params := SearchParams{From: from, pit: pit}
+	for sent := 0; sent < size; {
+		params.Size = min(batchSize, size-sent)
+		result, err := search(ctx, params)
+		if err != nil {
+			return grpcStatus(err)
+		}
+		if err := send(toProtoResponse(result, from+sent)); err != nil {
+			return err
+		}
+
+		sent += len(result.Items)
+		if len(result.Items) < params.Size || result.lastSort == nil {
+			break
+		}
+		if result.pitID != "" {
+			pit = result.pitID
+		}
+		params.pit, params.searchAfter = pit, result.lastSort
+	}
+	return nil
+}
+
+// streamWindow validates the page of a streamed search and applies its defaults
+func streamWindow(page *rpc.Page) (from, size, batchSize int, err error) {
+	from, size, batchSize = int(page.GetFrom()), int(page.GetSize()), int(page.GetBatchSize())
+	if size == 0 {
+		size = defaultPageSize
+	}
+	if batchSize == 0 {
+		batchSize = maxPageSize
+	}
+	switch {
+	case from < 0 || size < 0:
+		return 0, 0, 0, httpapi.Invalid("from and size must not be negative")
+	case batchSize < 0 || batchSize > maxBatchSize:
+		return 0, 0, 0, httpapi.Invalid("batch_size must be between 1 and %d", maxBatchSize)
+	case from+min(batchSize, size) > maxResultWindow:
+		// Only the first page is read with from, the others follow it with search_after
+		return 0, 0, 0, httpapi.Invalid("from + batch_size must not exceed %d", maxResultWindow)
+	}
+	return from, size, batchSize, nil
+}
+
+func toProtoResponse(result *SearchResult, from int) *rpc.SearchResponse {
+	products := make([]*rpc.Product, 0, len(result.Items))
+	for _, item := range result.Items {
+		var createdAt *timestamppb.Timestamp
+		if !item.CreatedAt.IsZero() {
+			createdAt = timestamppb.New(item.CreatedAt)
+		}
+		products = append(products, &rpc.Product{
+			Id:          item.ID,
+			Name:        item.Name,
+			Description: item.Description,
+			Price:       item.Price,
+			Categories:  item.Categories,
+			Brand:       item.Brand,
+			InStock:     item.InStock,
+			Rating:      item.Rating,
+			CreatedAt:   createdAt,
+		})
+	}
+	return &rpc.SearchResponse{
+		Total:    result.Total,
+		From:     int32(from),
+		Products: products,
+		TimedOut: result.TimedOut,
+		Warnings: result.Warnings,
+	}
+}
+
+// grpcStatus maps validation and partial result errors, then defers to rpc.Status
+func grpcStatus(err error) error {
+	var validation *httpapi.ValidationError
+	switch {
+	case errors.As(err, &validation):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrPartialResults):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return rpc.Status(err)
+}
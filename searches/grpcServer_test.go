@@ -0,0 +1,223 @@
+package searches
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+
+	"Elastic-Search/rpc"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakePITCluster answers point in time and search requests like a cluster holding total products
+type fakePITCluster struct {
+	total int
+
+	mu       sync.Mutex
+	searches []map[string]interface{} // Bodies of the search requests
+	closed   []string                 // Ids of the closed points in time
+}
+
+func (c *fakePITCluster) respond(r *http.Request) (int, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var body map[string]interface{}
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/products/_pit":
+		return http.StatusOK, `{"id": "pit-0"}`
+	case r.Method == http.MethodDelete && r.URL.Path == "/_pit":
+		c.closed = append(c.closed, body["id"].(string))
+		return http.StatusOK, `{"succeeded": true, "num_freed": 1}`
+	case r.URL.Path != "/_search":
+		return http.StatusNotFound, `{"error": "unexpected request", "status": 404}`
+	}
+
+	// Hits are numbered by their position; search_after continues behind the last one
+	c.searches = append(c.searches, body)
+	from, _ := body["from"].(float64)
+	offset := int(from)
+	if searchAfter, ok := body["search_after"].([]interface{}); ok {
+		offset = int(searchAfter[1].(float64)) + 1
+	}
+	var hits []interface{}
+	for i := offset; i < min(offset+int(body["size"].(float64)), c.total); i++ {
+		hits = append(hits, map[string]interface{}{
+			"_id":     fmt.Sprint(i),
+			"_source": map[string]interface{}{"id": fmt.Sprint(i)},
+			"sort":    []interface{}{1.0, i},
+		})
+	}
+	response, _ := json.Marshal(map[string]interface{}{
+		"pit_id":    fmt.Sprintf("pit-%d", len(c.searches)),
+		"timed_out": false,
+		"hits": map[string]interface{}{
+			"total": map[string]interface{}{"value": min(c.total, 10000), "relation": "gte"},
+			"hits":  hits,
+		},
+	})
+	return http.StatusOK, string(response)
+}
+
+// newHarness serves the search service of sc in process
+func newHarness(t *testing.T, sc *SearchClient) rpc.SearchServiceClient {
+	t.Helper()
+	harness, err := rpc.NewHarness(nil, func(server *grpc.Server) {
+		rpc.RegisterSearchServiceServer(server, NewSearchService(sc))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = harness.Close() })
+	return harness.SearchClient()
+}
+
+func TestStreamBeyondResultWindow(t *testing.T) {
+	cluster := &fakePITCluster{total: 20000}
+	client := newHarness(t, newTestClient(t, &fakeTransport{respond: cluster.respond}))
+
+	stream, err := client.Match(context.Background(), &rpc.MatchRequest{
+		Field: "name",
+		Query: "laptop",
+		Page:  &rpc.Page{From: 9990, Size: 25, BatchSize: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var froms []int32
+	var ids []string
+	for {
+		response, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		froms = append(froms, response.GetFrom())
+		for _, product := range response.GetProducts() {
+			ids = append(ids, product.GetId())
+		}
+	}
+
+	if want := []int32{9990, 10000, 10010}; !slices.Equal(froms, want) {
+		t.Errorf("batches start at %v, want %v", froms, want)
+	}
+	if len(ids) != 25 || ids[0] != "9990" || ids[24] != "10014" {
+		t.Errorf("streamed hits %v, want 25 from 9990 to 10014", ids)
+	}
+
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	for i, body := range cluster.searches {
+		pit, _ := body["pit"].(map[string]interface{})
+		if want := fmt.Sprintf("pit-%d", i); pit["id"] != want {
+			t.Errorf("search %d read point in time %v, want %s", i, pit["id"], want)
+		}
+		if _, ok := body["search_after"]; ok != (i > 0) {
+			t.Errorf("search %d has search_after %v", i, body["search_after"])
+		}
+		if _, ok := body["from"]; ok != (i == 0) {
+			t.Errorf("search %d has from %v", i, body["from"])
+		}
+	}
+	if want := fmt.Sprintf("pit-%d", len(cluster.searches)); len(cluster.closed) != 1 || cluster.closed[0] != want {
+		t.Errorf("closed points in time %v, want [%s]", cluster.closed, want)
+	}
+}
+
+func TestStreamStopsAtLastHit(t *testing.T) {
+	cluster := &fakePITCluster{total: 15}
+	client := newHarness(t, newTestClient(t, &fakeTransport{respond: cluster.respond}))
+
+	stream, err := client.Match(context.Background(), &rpc.MatchRequest{
+		Field: "name",
+		Query: "laptop",
+		Page:  &rpc.Page{Size: 100, BatchSize: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := 0
+	for {
+		response, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		hits += len(response.GetProducts())
+	}
+	if hits != 15 {
+		t.Errorf("streamed %d hits, want 15", hits)
+	}
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	if len(cluster.searches) != 2 {
+		t.Errorf("sent %d searches, want 2", len(cluster.searches))
+	}
+}
+
+func TestSearchServiceValidation(t *testing.T) {
+	client := newHarness(t, newTestClient(t, &fakeTransport{status: http.StatusOK, body: searchHits}))
+
+	unknownClause, err := structpb.NewStruct(map[string]interface{}{"script": map[string]interface{}{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		call func(ctx context.Context) (grpc.ClientStream, error)
+	}{
+		{"match without query", func(ctx context.Context) (grpc.ClientStream, error) {
+			return client.Match(ctx, &rpc.MatchRequest{Field: "name"})
+		}},
+		{"match on a field that is not searchable", func(ctx context.Context) (grpc.ClientStream, error) {
+			return client.Match(ctx, &rpc.MatchRequest{Field: "price", Query: "laptop"})
+		}},
+		{"bool with an unknown clause", func(ctx context.Context) (grpc.ClientStream, error) {
+			return client.Bool(ctx, &rpc.BoolRequest{Clauses: unknownClause})
+		}},
+		{"bool without clauses", func(ctx context.Context) (grpc.ClientStream, error) {
+			return client.Bool(ctx, &rpc.BoolRequest{})
+		}},
+		{"range without bounds", func(ctx context.Context) (grpc.ClientStream, error) {
+			return client.Range(ctx, &rpc.RangeRequest{Field: "price"})
+		}},
+		{"first batch beyond the result window", func(ctx context.Context) (grpc.ClientStream, error) {
+			return client.Match(ctx, &rpc.MatchRequest{
+				Field: "name",
+				Query: "laptop",
+				Page:  &rpc.Page{From: 9995, BatchSize: 10},
+			})
+		}},
+		{"batch size above the maximum", func(ctx context.Context) (grpc.ClientStream, error) {
+			return client.Match(ctx, &rpc.MatchRequest{Field: "name", Query: "laptop", Page: &rpc.Page{BatchSize: 1001}})
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stream, err := test.call(context.Background())
+			if err == nil {
+				err = stream.RecvMsg(&rpc.SearchResponse{})
+			}
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("error = %v, want code %s", err, codes.InvalidArgument)
+			}
+		})
+	}
+}
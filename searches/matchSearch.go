@@ -1,4 +1,4 @@
-package main
+package searches
 
 import "context"
 
@@ -18,5 +18,5 @@ func (sc *SearchClient) MatchSearch(
 		},
 	}
 
-	return sc.executeSearch(ctx, searchQuery)
+	return sc.executeSearch(ctx, "match", params, searchQuery)
 }
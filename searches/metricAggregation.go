@@ -0,0 +1,211 @@
+package searches
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PercentilesAgg estimates the given percentiles of a numeric field.
+// Compression trades memory for accuracy of the t-digest sketch; zero keeps the Elasticsearch default (100).
+func PercentilesAgg(field string, percents []float64, compression float64) map[string]interface{} {
+	percentiles := map[string]interface{}{
+		"field": field,
+		"keyed": false,
+	}
+	if len(percents) > 0 {
+		percentiles["percents"] = percents
+	}
+	if compression > 0 {
+		percentiles["tdigest"] = map[string]interface{}{"compression": compression}
+	}
+	return map[string]interface{}{"percentiles": percentiles}
+}
+
+// PercentileRanksAgg estimates which percentile each of the given values falls on
+func PercentileRanksAgg(field string, values []float64, compression float64) map[string]interface{} {
+	ranks := map[string]interface{}{
+		"field":  field,
+		"values": values,
+		"keyed":  false,
+	}
+	if compression > 0 {
+		ranks["tdigest"] = map[string]interface{}{"compression": compression}
+	}
+	return map[string]interface{}{"percentile_ranks": ranks}
+}
+
+// CardinalityAgg approximates the number of distinct values of a field, e.g. distinct brands.
+// Counts below precisionThreshold are close to exact; zero keeps the Elasticsearch default (3000).
+func CardinalityAgg(field string, precisionThreshold int) map[string]interface{} {
+	cardinality := map[string]interface{}{
+		"field": field,
+	}
+	if precisionThreshold > 0 {
+		cardinality["precision_threshold"] = precisionThreshold
+	}
+	return map[string]interface{}{"cardinality": cardinality}
+}
+
+// ExtendedStatsAgg computes count, min, max, avg, sum, variance and standard deviation bounds.
+// Sigma controls how many standard deviations the bounds span; zero keeps the Elasticsearch default (2).
+func ExtendedStatsAgg(field string, sigma float64) map[string]interface{} {
+	stats := map[string]interface{}{
+		"field": field,
+	}
+	if sigma > 0 {
+		stats["sigma"] = sigma
+	}
+	return map[string]interface{}{"extended_stats": stats}
+}
+
+// TopHitsAgg returns the best size documents of each bucket, e.g. the highest rated product per brand
+func TopHitsAgg(size int, sortField, order string) map[string]interface{} {
+	topHits := map[string]interface{}{
+		"size": size,
+	}
+	if sortField != "" {
+		topHits["sort"] = []map[string]interface{}{
+			{sortField: map[string]interface{}{"order": order}},
+		}
+	}
+	return map[string]interface{}{"top_hits": topHits}
+}
+
+// PercentileValue is a single percentile (or percentile rank) estimate
+type PercentileValue struct {
+	Key   float64  `json:"key"`
+	Value *float64 `json:"value"` // nil when the field had no values
+}
+
+// ExtendedStats is the result of an extended_stats aggregation
+type ExtendedStats struct {
+	Count              int64    `json:"count"`
+	Min                *float64 `json:"min"`
+	Max                *float64 `json:"max"`
+	Avg                *float64 `json:"avg"`
+	Sum                float64  `json:"sum"`
+	SumOfSquares       *float64 `json:"sum_of_squares"`
+	Variance           *float64 `json:"variance"`
+	StdDeviation       *float64 `json:"std_deviation"`
+	StdDeviationBounds struct {
+		Upper *float64 `json:"upper"`
+		Lower *float64 `json:"lower"`
+	} `json:"std_deviation_bounds"`
+}
+
+func decodePercentiles(data []byte) ([]PercentileValue, error) {
+	var agg struct {
+		Values []PercentileValue `json:"values"`
+	}
+	if err := json.Unmarshal(data, &agg); err != nil {
+		return nil, err
+	}
+	return agg.Values, nil
+}
+
+func decodeCardinality(data []byte) (int64, error) {
+	var agg struct {
+		Value int64 `json:"value"`
+	}
+	if err := json.Unmarshal(data, &agg); err != nil {
+		return 0, err
+	}
+	return agg.Value, nil
+}
+
+func decodeExtendedStats(data []byte) (*ExtendedStats, error) {
+	var stats ExtendedStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func decodeTopHits(data []byte) ([]Product, error) {
+	var agg struct {
+		Hits struct {
+			Hits []struct {
+				Source Product `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(data, &agg); err != nil {
+		return nil, err
+	}
+
+	products := make([]Product, 0, len(agg.Hits.Hits))
+	for _, hit := range agg.Hits.Hits {
+		products = append(products, hit.Source)
+	}
+	return products, nil
+}
+
+// decodeAgg runs decode on the raw JSON of a named aggregation and wraps errors with its name
+func decodeAgg[T any](name string, data []byte, err error, decode func([]byte) (T, error)) (T, error) {
+	var zero T
+	if err != nil {
+		return zero, err
+	}
+	value, err := decode(data)
+	if err != nil {
+		return zero, fmt.Errorf("error parsing aggregation %q: %w", name, err)
+	}
+	return value, nil
+}
+
+// Percentiles decodes a named percentiles or percentile_ranks aggregation
+func (r *SearchResult) Percentiles(name string) ([]PercentileValue, error) {
+	data, err := r.aggregation(name)
+	return decodeAgg(name, data, err, decodePercentiles)
+}
+
+// Cardinality decodes a named cardinality aggregation
+func (r *SearchResult) Cardinality(name string) (int64, error) {
+	data, err := r.aggregation(name)
+	return decodeAgg(name, data, err, decodeCardinality)
+}
+
+// ExtendedStats decodes a named extended_stats aggregation
+func (r *SearchResult) ExtendedStats(name string) (*ExtendedStats, error) {
+	data, err := r.aggregation(name)
+	return decodeAgg(name, data, err, decodeExtendedStats)
+}
+
+// TopHits decodes a named top level top_hits aggregation
+func (r *SearchResult) TopHits(name string) ([]Product, error) {
+	data, err := r.aggregation(name)
+	return decodeAgg(name, data, err, decodeTopHits)
+}
+
+// subAggregation returns the raw JSON of a named sub aggregation of the bucket
+func (b AggBucket) subAggregation(name string) ([]byte, error) {
+	data, ok := b.subAggs[name]
+	if !ok {
+		return nil, fmt.Errorf("aggregation %q not found in bucket %v", name, b.Key)
+	}
+	return data, nil
+}
+
+// Percentiles decodes a named percentiles or percentile_ranks sub aggregation of the bucket
+func (b AggBucket) Percentiles(name string) ([]PercentileValue, error) {
+	data, err := b.subAggregation(name)
+	return decodeAgg(name, data, err, decodePercentiles)
+}
+
+// Cardinality decodes a named cardinality sub aggregation of the bucket
+func (b AggBucket) Cardinality(name string) (int64, error) {
+	data, err := b.subAggregation(name)
+	return decodeAgg(name, data, err, decodeCardinality)
+}
+
+// ExtendedStats decodes a named extended_stats sub aggregation of the bucket
+func (b AggBucket) ExtendedStats(name string) (*ExtendedStats, error) {
+	data, err := b.subAggregation(name)
+	return decodeAgg(name, data, err, decodeExtendedStats)
+}
+
+// TopHits decodes a named top_hits sub aggregation of the bucket
+func (b AggBucket) TopHits(name string) ([]Product, error) {
+	data, err := b.subAggregation(name)
+	return decodeAgg(name, data, err, decodeTopHits)
+}
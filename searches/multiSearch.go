@@ -1,4 +1,4 @@
-package main
+package searches
 
 import "context"
 
@@ -6,9 +6,12 @@ func (sc *SearchClient) MultiMatchSearch(
 	ctx context.Context,
 	query string,
 	fields []string,
+	params SearchParams,
 ) (*SearchResult, error) {
 	// Search across multiple fields. Good for searching in title, description, etc.
 	searchQuery := map[string]interface{}{
+		"from": params.From,
+		"size": params.Size,
 		"query": map[string]interface{}{
 			"multi_match": map[string]interface{}{
 				"query":  query,
@@ -17,5 +20,5 @@ func (sc *SearchClient) MultiMatchSearch(
 		},
 	}
 
-	return sc.executeSearch(ctx, searchQuery)
+	return sc.executeSearch(ctx, "multi_match", params, searchQuery)
 }
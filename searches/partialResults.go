@@ -0,0 +1,88 @@
+package searches
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PartialResultsMode controls how searches with failed shards or timeouts are reported
+type PartialResultsMode int
+
+const (
+	// PartialResultsLenient returns partial results and describes what is missing in SearchResult.Warnings
+	PartialResultsLenient PartialResultsMode = iota
+	// PartialResultsStrict turns partial results into a *PartialResultsError
+	PartialResultsStrict
+)
+
+// ErrPartialResults is matched by *PartialResultsError through errors.Is
+var ErrPartialResults = errors.New("search returned partial results")
+
+// ShardStats is the _shards section of a search response
+type ShardStats struct {
+	Total      int            `json:"total"`
+	Successful int            `json:"successful"`
+	Skipped    int            `json:"skipped"`
+	Failed     int            `json:"failed"`
+	Failures   []ShardFailure `json:"failures,omitempty"`
+}
+
+// ShardFailure describes why a single shard could not answer the search
+type ShardFailure struct {
+	Shard  int    `json:"shard"`
+	Index  string `json:"index"`
+	Node   string `json:"node"`
+	Reason struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"reason"`
+}
+
+// PartialResultsError is returned in strict mode when shards failed or the search timed out
+type PartialResultsError struct {
+	TimedOut bool
+	Shards   ShardStats
+}
+
+func (e *PartialResultsError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrPartialResults, strings.Join(partialWarnings(e.TimedOut, e.Shards), "; "))
+}
+
+func (e *PartialResultsError) Unwrap() error {
+	return ErrPartialResults
+}
+
+// Partial reports whether the result is missing hits because of failed shards or a timeout
+func (r *SearchResult) Partial() bool {
+	return r.TimedOut || r.Shards.Failed > 0
+}
+
+// checkPartial applies the client's partial results mode to a decoded search result
+func (sc *SearchClient) checkPartial(result *SearchResult) (*SearchResult, error) {
+	if !result.Partial() {
+		return result, nil
+	}
+	if sc.partialMode == PartialResultsStrict {
+		return nil, &PartialResultsError{TimedOut: result.TimedOut, Shards: result.Shards}
+	}
+	result.Warnings = append(result.Warnings, partialWarnings(result.TimedOut, result.Shards)...)
+	return result, nil
+}
+
+func partialWarnings(timedOut bool, shards ShardStats) []string {
+	var warnings []string
+	if timedOut {
+		warnings = append(warnings, "search timed out before all shards responded")
+	}
+	if shards.Failed > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d of %d shards failed", shards.Failed, shards.Total))
+	}
+	for _, failure := range shards.Failures {
+		warnings = append(warnings, fmt.Sprintf(
+			"shard %d of %s on node %s: %s: %s",
+			failure.Shard, failure.Index, failure.Node, failure.Reason.Type, failure.Reason.Reason,
+		))
+	}
+	return warnings
+}
@@ -1,4 +1,4 @@
-package main
+package searches
 
 import "context"
 
@@ -6,9 +6,12 @@ func (sc *SearchClient) PhraseSearch(
 	ctx context.Context,
 	field, phrase string,
 	slop int,
+	params SearchParams,
 ) (*SearchResult, error) {
 	// Phrase search with slop. Slop in phrase search refers to the number of allowed word movements or rearrangements in a search query.Without Slop: The search engine requires an exact match of the words in the specified order.
 	searchQuery := map[string]interface{}{
+		"from": params.From,
+		"size": params.Size,
 		"query": map[string]interface{}{
 			"match_phrase": map[string]interface{}{
 				field: map[string]interface{}{
@@ -19,5 +22,5 @@ func (sc *SearchClient) PhraseSearch(
 		},
 	}
 
-	return sc.executeSearch(ctx, searchQuery)
+	return sc.executeSearch(ctx, "phrase", params, searchQuery)
 }
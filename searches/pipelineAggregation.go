@@ -0,0 +1,206 @@
+package searches
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Aggregation builders. Each returns the body of a single aggregation so they can be
+// nested into the map passed to AggregationSearch, e.g. aggs["per_month"] = DateHistogramAgg(...)
+
+// DateHistogramAgg buckets documents by a calendar interval (day, week, month, ...)
+func DateHistogramAgg(
+	field, interval string,
+	subAggs map[string]interface{},
+) map[string]interface{} {
+	agg := map[string]interface{}{
+		"date_histogram": map[string]interface{}{
+			"field":             field,
+			"calendar_interval": interval,
+			"min_doc_count":     0,
+		},
+	}
+	if len(subAggs) > 0 {
+		agg["aggs"] = subAggs
+	}
+	return agg
+}
+
+// TermsAgg buckets documents by the distinct values of a keyword field
+func TermsAgg(field string, size int, subAggs map[string]interface{}) map[string]interface{} {
+	agg := map[string]interface{}{
+		"terms": map[string]interface{}{
+			"field": field,
+			"size":  size,
+		},
+	}
+	if len(subAggs) > 0 {
+		agg["aggs"] = subAggs
+	}
+	return agg
+}
+
+// MetricAgg builds a single value metric aggregation such as avg, sum, min or max
+func MetricAgg(kind, field string) map[string]interface{} {
+	return map[string]interface{}{
+		kind: map[string]interface{}{
+			"field": field,
+		},
+	}
+}
+
+// Pipeline aggregations reference other aggregations through buckets_path.
+// Parent pipelines (derivative, moving_fn, bucket_sort) live inside a multi-bucket
+// aggregation and point at a sibling metric, e.g. "avg_price" or "_count".
+// Sibling pipelines (max_bucket, avg_bucket) live next to the multi-bucket
+// aggregation and use ">" to reach into it, e.g. "per_month>avg_price".
+
+// MovingAverage computes an unweighted moving average over the previous window buckets
+func MovingAverage(bucketsPath string, window int) map[string]interface{} {
+	return map[string]interface{}{
+		"moving_fn": map[string]interface{}{
+			"buckets_path": bucketsPath,
+			"window":       window,
+			"script":       "MovingFunctions.unweightedAvg(values)",
+		},
+	}
+}
+
+// Derivative computes the change of a metric between consecutive histogram buckets
+func Derivative(bucketsPath string) map[string]interface{} {
+	return map[string]interface{}{
+		"derivative": map[string]interface{}{
+			"buckets_path": bucketsPath,
+		},
+	}
+}
+
+// CumulativeSum computes the running total of a metric across histogram buckets
+func CumulativeSum(bucketsPath string) map[string]interface{} {
+	return map[string]interface{}{
+		"cumulative_sum": map[string]interface{}{
+			"buckets_path": bucketsPath,
+		},
+	}
+}
+
+// BucketSort keeps the top size buckets of the parent aggregation ordered by a metric
+func BucketSort(sortPath, order string, size int) map[string]interface{} {
+	return map[string]interface{}{
+		"bucket_sort": map[string]interface{}{
+			"sort": []map[string]interface{}{
+				{sortPath: map[string]interface{}{"order": order}},
+			},
+			"size": size,
+		},
+	}
+}
+
+// SiblingPipeline builds a sibling pipeline aggregation such as max_bucket, min_bucket or avg_bucket
+func SiblingPipeline(kind, bucketsPath string) map[string]interface{} {
+	return map[string]interface{}{
+		kind: map[string]interface{}{
+			"buckets_path": bucketsPath,
+		},
+	}
+}
+
+// AggValue is the result of a single value metric or pipeline aggregation
+type AggValue struct {
+	Value *float64 `json:"value"`          // nil when there was nothing to compute, e.g. the first derivative bucket
+	Keys  []string `json:"keys,omitempty"` // Bucket keys reported by max_bucket/min_bucket
+}
+
+// AggBucket is a bucket of a terms or histogram aggregation together with its sub aggregation values
+type AggBucket struct {
+	Key         interface{}         `json:"key"`
+	KeyAsString string              `json:"key_as_string,omitempty"`
+	DocCount    int64               `json:"doc_count"`
+	Values      map[string]AggValue `json:"values,omitempty"`
+
+	subAggs map[string]json.RawMessage
+}
+
+// Value returns the value of a named sub aggregation and whether it was present
+func (b AggBucket) Value(name string) (float64, bool) {
+	v, ok := b.Values[name]
+	if !ok || v.Value == nil {
+		return 0, false
+	}
+	return *v.Value, true
+}
+
+func (b *AggBucket) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	b.Values = map[string]AggValue{}
+	b.subAggs = map[string]json.RawMessage{}
+	for name, value := range raw {
+		var err error
+		switch name {
+		case "key":
+			err = json.Unmarshal(value, &b.Key)
+		case "key_as_string":
+			err = json.Unmarshal(value, &b.KeyAsString)
+		case "doc_count":
+			err = json.Unmarshal(value, &b.DocCount)
+		default:
+			// Every sub aggregation is kept raw; single value ones are also exposed through Values
+			b.subAggs[name] = value
+			var agg AggValue
+			if json.Unmarshal(value, &agg) == nil && agg.Value != nil {
+				b.Values[name] = agg
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("error parsing bucket %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// aggregation returns the raw JSON of a top level aggregation by name
+func (r *SearchResult) aggregation(name string) ([]byte, error) {
+	aggs, ok := r.Aggs.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no aggregations in result")
+	}
+	agg, ok := aggs[name]
+	if !ok {
+		return nil, fmt.Errorf("aggregation %q not found", name)
+	}
+	return json.Marshal(agg)
+}
+
+// AggBuckets decodes the buckets of a named terms or histogram aggregation
+func (r *SearchResult) AggBuckets(name string) ([]AggBucket, error) {
+	aggBytes, err := r.aggregation(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var agg struct {
+		Buckets []AggBucket `json:"buckets"`
+	}
+	if err := json.Unmarshal(aggBytes, &agg); err != nil {
+		return nil, fmt.Errorf("error parsing aggregation %q: %w", name, err)
+	}
+	return agg.Buckets, nil
+}
+
+// AggValue decodes a named single value metric or sibling pipeline aggregation
+func (r *SearchResult) AggValue(name string) (*AggValue, error) {
+	aggBytes, err := r.aggregation(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var value AggValue
+	if err := json.Unmarshal(aggBytes, &value); err != nil {
+		return nil, fmt.Errorf("error parsing aggregation %q: %w", name, err)
+	}
+	return &value, nil
+}
@@ -0,0 +1,88 @@
+package searches
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"Elastic-Search/eserrors"
+	"Elastic-Search/logging"
+	"Elastic-Search/retry"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// pitKeepAlive is how long a point in time outlives the last page read from it
+const pitKeepAlive = "1m"
+
+// openPIT opens a point in time of the index. Pages read from it with search_after see
+// the index as it was when it opened and are not bounded by the result window.
+func (sc *SearchClient) openPIT(ctx context.Context) (string, error) {
+	start := time.Now()
+	// An extra point in time opened by a retry expires after pitKeepAlive
+	res, err := retry.Do(ctx, sc.retry.For("open point in time", true), func(ctx context.Context) (*esapi.Response, error) {
+		return sc.client.OpenPointInTime(
+			[]string{sc.index},
+			pitKeepAlive,
+			sc.client.OpenPointInTime.WithContext(ctx),
+		)
+	})
+	sc.metrics.ObserveRequest("search", "open_pit", sc.index, start, res, err)
+	if err != nil {
+		return "", fmt.Errorf("error opening point in time: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			sc.log().WarnContext(ctx, "error closing body", logging.KeyError, err)
+		}
+	}(res.Body)
+
+	if err := eserrors.FromResponse("open point in time", res); err != nil {
+		return "", err
+	}
+
+	var opened struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&opened); err != nil {
+		return "", fmt.Errorf("error parsing response: %w", err)
+	}
+	return opened.ID, nil
+}
+
+// closePIT releases a point in time. It runs after the caller's context may be done, so
+// it has a context of its own, and only logs failures: the point in time expires anyway.
+func (sc *SearchClient) closePIT(ctx context.Context, id string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	body, err := json.Marshal(map[string]string{"id": id})
+	if err != nil {
+		sc.log().WarnContext(ctx, "error marshaling point in time", logging.KeyError, err)
+		return
+	}
+	start := time.Now()
+	res, err := sc.client.ClosePointInTime(
+		sc.client.ClosePointInTime.WithContext(ctx),
+		sc.client.ClosePointInTime.WithBody(bytes.NewReader(body)),
+	)
+	sc.metrics.ObserveRequest("search", "close_pit", sc.index, start, res, err)
+	if err != nil {
+		sc.log().WarnContext(ctx, "error closing point in time", logging.KeyIndex, sc.index, logging.KeyError, err)
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			sc.log().WarnContext(ctx, "error closing body", logging.KeyError, err)
+		}
+	}(res.Body)
+
+	if err := eserrors.FromResponse("close point in time", res); err != nil {
+		sc.log().WarnContext(ctx, "error closing point in time", logging.KeyIndex, sc.index, logging.KeyError, err)
+	}
+}
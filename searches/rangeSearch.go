@@ -1,4 +1,4 @@
-package main
+package searches
 
 import "context"
 
@@ -6,9 +6,12 @@ func (sc *SearchClient) RangeSearch(
 	ctx context.Context,
 	field string,
 	ranges map[string]interface{},
+	params SearchParams,
 ) (*SearchResult, error) {
 	// Range query for numeric/date fields
 	searchQuery := map[string]interface{}{
+		"from": params.From,
+		"size": params.Size,
 		"query": map[string]interface{}{
 			"range": map[string]interface{}{
 				field: ranges,
@@ -16,5 +19,5 @@ func (sc *SearchClient) RangeSearch(
 		},
 	}
 
-	return sc.executeSearch(ctx, searchQuery)
+	return sc.executeSearch(ctx, "range", params, searchQuery)
 }
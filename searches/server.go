@@ -0,0 +1,324 @@
+package searches
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"Elastic-Search/eserrors"
+	"Elastic-Search/httpapi"
+)
+
+// Pagination limits of the REST API. from+size may not exceed the index max_result_window.
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+	maxResultWindow = 10000
+	maxBodyBytes    = 1 << 20
+)
+
+// Fields and bool clauses clients may use; anything else is rejected before reaching Elasticsearch
+var (
+	textFields  = []string{"name", "description", "brand", "categories"}
+	rangeFields = []string{"price", "rating", "created_at"}
+	boolClauses = []string{"must", "should", "must_not", "filter", "minimum_should_match", "boost"}
+)
+
+// SearchServer exposes the SearchClient query kinds as JSON endpoints
+type SearchServer struct {
+	sc  *SearchClient
+	api *httpapi.API
+}
+
+// NewSearchServer creates a server in front of sc
+func NewSearchServer(sc *SearchClient, logger *slog.Logger) *SearchServer {
+	return &SearchServer{sc: sc, api: &httpapi.API{
+		Name:         "search API",
+		Logger:       logger,
+		Status:       errorStatus,
+		MaxBodyBytes: maxBodyBytes,
+	}}
+}
+
+// Handler returns the routes of the search API:
+//
+//	GET  /search/match?field=name&q=laptop
+//	GET  /search/multi?q=gaming+laptop&fields=name,description
+//	POST /search/bool      body: {"must": [...], "filter": [...]}
+//	GET  /search/range?field=price&gte=1000&lte=2000
+//	GET  /search/fuzzy?field=name&q=lapto&fuzziness=AUTO
+//	GET  /search/phrase?field=description&q=gaming+laptop&slop=1
+//	POST /search/agg       body: {"aggs": {...}}
+//
+// Every search accepts from and size query parameters.
+func (s *SearchServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /search/match", s.handleMatch)
+	mux.HandleFunc("GET /search/multi", s.handleMultiMatch)
+	mux.HandleFunc("POST /search/bool", s.handleBool)
+	mux.HandleFunc("GET /search/range", s.handleRange)
+	mux.HandleFunc("GET /search/fuzzy", s.handleFuzzy)
+	mux.HandleFunc("GET /search/phrase", s.handlePhrase)
+	mux.HandleFunc("POST /search/agg", s.handleAggregation)
+	mux.HandleFunc("GET /healthz", httpapi.Health(s.sc.BreakerState))
+	return mux
+}
+
+// ListenAndServe serves the search API on addr until ctx is cancelled
+func (s *SearchServer) ListenAndServe(ctx context.Context, addr string) error {
+	return s.api.ListenAndServe(ctx, addr, s.Handler())
+}
+
+// searchResponse is the JSON body of a successful search
+type searchResponse struct {
+	*SearchResult
+	From int `json:"from"`
+	Size int `json:"size"`
+}
+
+func (s *SearchServer) handleMatch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, err := pageParams(q.Get("from"), q.Get("size"))
+	if err == nil {
+		err = requireField(q.Get("field"), textFields)
+	}
+	if err == nil {
+		err = requireParam("q", q.Get("q"))
+	}
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+
+	result, err := s.sc.MatchSearch(r.Context(), q.Get("field"), q.Get("q"), page)
+	s.writeResult(w, r, result, page, err)
+}
+
+func (s *SearchServer) handleMultiMatch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, err := pageParams(q.Get("from"), q.Get("size"))
+	if err == nil {
+		err = requireParam("q", q.Get("q"))
+	}
+
+	fields := textFields
+	if err == nil && q.Get("fields") != "" {
+		fields = strings.Split(q.Get("fields"), ",")
+		for _, field := range fields {
+			if err = requireField(field, textFields); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+
+	result, err := s.sc.MultiMatchSearch(r.Context(), q.Get("q"), fields, page)
+	s.writeResult(w, r, result, page, err)
+}
+
+func (s *SearchServer) handleBool(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, err := pageParams(q.Get("from"), q.Get("size"))
+	var clauses map[string]interface{}
+	if err == nil {
+		err = s.api.Decode(w, r, &clauses)
+	}
+	if err == nil {
+		err = validateBoolClauses(clauses)
+	}
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+
+	result, err := s.sc.BoolSearch(r.Context(), clauses, page)
+	s.writeResult(w, r, result, page, err)
+}
+
+func (s *SearchServer) handleRange(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, err := pageParams(q.Get("from"), q.Get("size"))
+	if err == nil {
+		err = requireField(q.Get("field"), rangeFields)
+	}
+
+	ranges := map[string]interface{}{}
+	for _, op := range []string{"gt", "gte", "lt", "lte"} {
+		if err != nil || q.Get(op) == "" {
+			continue
+		}
+		// Numbers are sent as numbers; anything else (dates, date math) as strings
+		if n, parseErr := strconv.ParseFloat(q.Get(op), 64); parseErr == nil && !math.IsInf(n, 0) && !math.IsNaN(n) {
+			ranges[op] = n
+		} else {
+			ranges[op] = q.Get(op)
+		}
+	}
+	if err == nil && len(ranges) == 0 {
+		err = httpapi.Invalid("range search needs at least one of gt, gte, lt, lte")
+	}
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+
+	result, err := s.sc.RangeSearch(r.Context(), q.Get("field"), ranges, page)
+	s.writeResult(w, r, result, page, err)
+}
+
+func (s *SearchServer) handleFuzzy(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, err := pageParams(q.Get("from"), q.Get("size"))
+	if err == nil {
+		err = requireField(q.Get("field"), textFields)
+	}
+	if err == nil {
+		err = requireParam("q", q.Get("q"))
+	}
+
+	var fuzziness interface{} = "AUTO"
+	if err == nil && q.Get("fuzziness") != "" && q.Get("fuzziness") != "AUTO" {
+		distance, convErr := strconv.Atoi(q.Get("fuzziness"))
+		if convErr != nil || distance < 0 || distance > 2 {
+			err = httpapi.Invalid("fuzziness must be AUTO, 0, 1 or 2")
+		}
+		fuzziness = distance
+	}
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+
+	result, err := s.sc.FuzzySearch(r.Context(), q.Get("field"), q.Get("q"), fuzziness, page)
+	s.writeResult(w, r, result, page, err)
+}
+
+func (s *SearchServer) handlePhrase(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, err := pageParams(q.Get("from"), q.Get("size"))
+	if err == nil {
+		err = requireField(q.Get("field"), textFields)
+	}
+	if err == nil {
+		err = requireParam("q", q.Get("q"))
+	}
+
+	slop := 0
+	if err == nil && q.Get("slop") != "" {
+		var convErr error
+		slop, convErr = strconv.Atoi(q.Get("slop"))
+		if convErr != nil || slop < 0 || slop > 10 {
+			err = httpapi.Invalid("slop must be an integer between 0 and 10")
+		}
+	}
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+
+	result, err := s.sc.PhraseSearch(r.Context(), q.Get("field"), q.Get("q"), slop, page)
+	s.writeResult(w, r, result, page, err)
+}
+
+func (s *SearchServer) handleAggregation(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Aggs map[string]interface{} `json:"aggs"`
+	}
+	err := s.api.Decode(w, r, &body)
+	if err == nil && len(body.Aggs) == 0 {
+		err = httpapi.Invalid("aggs must contain at least one aggregation")
+	}
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+
+	result, err := s.sc.AggregationSearch(r.Context(), body.Aggs)
+	s.writeResult(w, r, result, SearchParams{}, err)
+}
+
+// pageParams parses and validates the from and size query parameters
+func pageParams(fromParam, sizeParam string) (SearchParams, error) {
+	page := SearchParams{From: 0, Size: defaultPageSize}
+
+	var err error
+	if fromParam != "" {
+		if page.From, err = strconv.Atoi(fromParam); err != nil || page.From < 0 {
+			return page, httpapi.Invalid("from must be a non-negative integer")
+		}
+	}
+	if sizeParam != "" {
+		if page.Size, err = strconv.Atoi(sizeParam); err != nil || page.Size < 0 || page.Size > maxPageSize {
+			return page, httpapi.Invalid("size must be an integer between 0 and %d", maxPageSize)
+		}
+	}
+	if page.From+page.Size > maxResultWindow {
+		return page, httpapi.Invalid("from + size must not exceed %d", maxResultWindow)
+	}
+	return page, nil
+}
+
+// validateBoolClauses checks the clauses of a bool query sent by the REST and gRPC APIs
+func validateBoolClauses(clauses map[string]interface{}) error {
+	for clause := range clauses {
+		if !slices.Contains(boolClauses, clause) {
+			return httpapi.Invalid("unknown bool clause %q", clause)
+		}
+	}
+	if len(clauses) == 0 {
+		return httpapi.Invalid("bool query needs at least one clause")
+	}
+	return nil
+}
+
+func requireParam(name, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return httpapi.Invalid("%s is required", name)
+	}
+	return nil
+}
+
+func requireField(field string, allowed []string) error {
+	if field == "" {
+		return httpapi.Invalid("field is required, one of %s", strings.Join(allowed, ", "))
+	}
+	if !slices.Contains(allowed, field) {
+		return httpapi.Invalid("field %q is not searchable here, use one of %s", field, strings.Join(allowed, ", "))
+	}
+	return nil
+}
+
+func (s *SearchServer) writeResult(
+	w http.ResponseWriter,
+	r *http.Request,
+	result *SearchResult,
+	page SearchParams,
+	err error,
+) {
+	if err != nil {
+		s.api.WriteError(w, r, err)
+		return
+	}
+	httpapi.WriteJSON(w, http.StatusOK, searchResponse{SearchResult: result, From: page.From, Size: page.Size})
+}
+
+// errorStatus maps the errors specific to searches to HTTP statuses
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, eserrors.ErrIndexNotFound):
+		return http.StatusNotFound, "index_not_found"
+	case errors.Is(err, eserrors.ErrBadRequest), errors.Is(err, eserrors.ErrMappingConflict):
+		return http.StatusBadRequest, "invalid_query"
+	case errors.Is(err, ErrPartialResults):
+		return http.StatusBadGateway, "partial_results"
+	}
+	return 0, ""
+}
@@ -0,0 +1,209 @@
+package searches
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Elastic-Search/httpapi"
+	"Elastic-Search/internal/estest"
+	"Elastic-Search/retry"
+)
+
+// fakeTransport answers every request with one canned Elasticsearch response
+type fakeTransport struct {
+	status   int
+	body     string
+	respond  func(r *http.Request) (int, string) // Optional: answers instead of status and body
+	requests int
+}
+
+func (f *fakeTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	f.requests++
+	status, body := f.status, f.body
+	if f.respond != nil {
+		status, body = f.respond(r)
+	}
+	return estest.Response(r, status, body), nil
+}
+
+const searchHits = `{
+	"took": 1,
+	"timed_out": false,
+	"_shards": {"total": 1, "successful": 1, "skipped": 0, "failed": 0},
+	"hits": {"total": {"value": 1, "relation": "eq"}, "hits": [
+		{"_id": "1", "_source": {"id": "1", "name": "Gaming Laptop", "price": 1299.99}}
+	]}
+}`
+
+// newTestClient returns a search client whose requests are answered by transport
+func newTestClient(t *testing.T, transport *fakeTransport) *SearchClient {
+	t.Helper()
+	client := estest.NewClient(t, transport)
+	policies := retry.Policies{Default: retry.NoRetry()}
+	return NewSearchClient(client, Config{Retry: &policies})
+}
+
+// newTestServer returns the handler of a search server whose cluster answers with status and body
+func newTestServer(t *testing.T, status int, body string) (http.Handler, *fakeTransport) {
+	t.Helper()
+	transport := &fakeTransport{status: status, body: body}
+	return NewSearchServer(newTestClient(t, transport), nil).Handler(), transport
+}
+
+func serve(handler http.Handler, method, target, contentType, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestSearchServerMatch(t *testing.T) {
+	handler, _ := newTestServer(t, http.StatusOK, searchHits)
+
+	w := serve(handler, http.MethodGet, "/search/match?field=name&q=laptop&from=5&size=20", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var response struct {
+		Total int64     `json:"total"`
+		Items []Product `json:"items"`
+		From  int       `json:"from"`
+		Size  int       `json:"size"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Total != 1 || len(response.Items) != 1 || response.Items[0].Name != "Gaming Laptop" {
+		t.Errorf("response = %+v, want the one hit of the cluster", response)
+	}
+	if response.From != 5 || response.Size != 20 {
+		t.Errorf("page = %d/%d, want 5/20", response.From, response.Size)
+	}
+}
+
+func TestSearchServerValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+	}{
+		{"match without q", http.MethodGet, "/search/match?field=name", "", ""},
+		{"match without field", http.MethodGet, "/search/match?q=laptop", "", ""},
+		{"match on a field that is not searchable", http.MethodGet, "/search/match?field=price&q=laptop", "", ""},
+		{"negative from", http.MethodGet, "/search/match?field=name&q=laptop&from=-1", "", ""},
+		{"size not a number", http.MethodGet, "/search/match?field=name&q=laptop&size=ten", "", ""},
+		{"size above the maximum", http.MethodGet, "/search/match?field=name&q=laptop&size=101", "", ""},
+		{"page beyond the result window", http.MethodGet, "/search/match?field=name&q=laptop&from=9950&size=100", "", ""},
+		{"multi match on an unknown field", http.MethodGet, "/search/multi?q=laptop&fields=name,secret", "", ""},
+		{"range without bounds", http.MethodGet, "/search/range?field=price", "", ""},
+		{"range on a text field", http.MethodGet, "/search/range?field=name&gte=1", "", ""},
+		{"fuzziness out of range", http.MethodGet, "/search/fuzzy?field=name&q=lapto&fuzziness=3", "", ""},
+		{"slop out of range", http.MethodGet, "/search/phrase?field=name&q=gaming+laptop&slop=11", "", ""},
+		{"bool with an unknown clause", http.MethodPost, "/search/bool", "application/json", `{"must": [], "script": {}}`},
+		{"bool without clauses", http.MethodPost, "/search/bool", "application/json", `{}`},
+		{"bool with invalid JSON", http.MethodPost, "/search/bool", "application/json", `{"must": [`},
+		{"bool with another content type", http.MethodPost, "/search/bool", "text/plain", `{"must": []}`},
+		{"aggregation without aggs", http.MethodPost, "/search/agg", "application/json", `{"aggs": {}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler, transport := newTestServer(t, http.StatusOK, searchHits)
+
+			w := serve(handler, test.method, test.target, test.contentType, test.body)
+			assertError(t, w, http.StatusBadRequest, "invalid_request")
+			if transport.requests != 0 {
+				t.Errorf("invalid request reached Elasticsearch")
+			}
+		})
+	}
+}
+
+func TestSearchServerUpstreamErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			"query rejected by the cluster",
+			http.StatusBadRequest,
+			`{"error": {"type": "search_phase_execution_exception", "reason": "all shards failed"}, "status": 400}`,
+			http.StatusBadRequest, "invalid_query",
+		},
+		{
+			"missing index",
+			http.StatusNotFound,
+			`{"error": {"type": "index_not_found_exception", "reason": "no such index [products]", "index": "products"}, "status": 404}`,
+			http.StatusNotFound, "index_not_found",
+		},
+		{
+			"rejected execution",
+			http.StatusTooManyRequests,
+			`{"error": {"type": "es_rejected_execution_exception", "reason": "rejected execution"}, "status": 429}`,
+			http.StatusTooManyRequests, "too_many_requests",
+		},
+		{
+			"cluster failure",
+			http.StatusInternalServerError,
+			`{"error": {"type": "illegal_state_exception", "reason": "boom"}, "status": 500}`,
+			http.StatusBadGateway, "upstream_error",
+		},
+		{
+			"cluster unavailable",
+			http.StatusServiceUnavailable,
+			`{"error": {"type": "cluster_block_exception", "reason": "blocked"}, "status": 503}`,
+			http.StatusServiceUnavailable, "unavailable",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler, transport := newTestServer(t, test.status, test.body)
+
+			w := serve(handler, http.MethodGet, "/search/match?field=name&q=laptop", "", "")
+			assertError(t, w, test.wantStatus, test.wantCode)
+			if transport.requests != 1 {
+				t.Errorf("sent %d requests, want 1", transport.requests)
+			}
+		})
+	}
+}
+
+func TestSearchServerHealth(t *testing.T) {
+	handler, _ := newTestServer(t, http.StatusOK, searchHits)
+
+	w := serve(handler, http.MethodGet, "/healthz", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if !strings.Contains(w.Body.String(), `"breaker":"closed"`) {
+		t.Errorf("body = %s, want the breaker state", w.Body)
+	}
+}
+
+// assertError checks the status of w and that its body is the error envelope with that status and code
+func assertError(t *testing.T, w *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d: %s", w.Code, status, w.Body)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+	var envelope httpapi.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&envelope); err != nil {
+		t.Fatalf("body is not the error envelope: %v", err)
+	}
+	if envelope.Error.Status != status || envelope.Error.Code != code || envelope.Error.Message == "" {
+		t.Errorf("error = %+v, want status %d and code %q with a message", envelope.Error, status, code)
+	}
+}
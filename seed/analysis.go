@@ -0,0 +1,75 @@
+package seed
+
+import (
+	"Elastic-Search/catalog"
+	"Elastic-Search/mapping"
+)
+
+// englishFilters stem English text and fold accents so "Café Laptops" matches "cafe laptop"
+var englishFilters = []string{
+	"lowercase",
+	"asciifolding",
+	"english_possessive_stemmer",
+	"english_stop",
+	"english_stemmer",
+}
+
+// productAnalysis returns the analysis settings of the products index: an English stemming
+// analyzer, an edge n-gram autocomplete analyzer, a lowercase keyword normalizer and the
+// synonym search analyzer backed by setID.
+func productAnalysis(setID string) mapping.Analysis {
+	analysis := mapping.Analysis{
+		Filter: map[string]interface{}{
+			"english_stop": map[string]interface{}{
+				"type":      "stop",
+				"stopwords": "_english_",
+			},
+			"english_stemmer": map[string]interface{}{
+				"type":     "stemmer",
+				"language": "english",
+			},
+			"english_possessive_stemmer": map[string]interface{}{
+				"type":     "stemmer",
+				"language": "possessive_english",
+			},
+			"autocomplete_edge_ngram": map[string]interface{}{
+				"type":     "edge_ngram",
+				"min_gram": 2,
+				"max_gram": 15,
+			},
+		},
+		Analyzer: map[string]mapping.Analyzer{
+			catalog.EnglishAnalyzer: {
+				Type:      "custom",
+				Tokenizer: "standard",
+				Filter:    englishFilters,
+			},
+			catalog.AutocompleteAnalyzer: {
+				Type:      "custom",
+				Tokenizer: "standard",
+				Filter:    []string{"lowercase", "asciifolding", "autocomplete_edge_ngram"},
+			},
+		},
+		Normalizer: map[string]mapping.Normalizer{
+			catalog.KeywordNormalizer: {
+				Type:   "custom",
+				Filter: []string{"lowercase", "asciifolding"},
+			},
+		},
+	}
+	addSynonymAnalysis(&analysis, setID)
+	return analysis
+}
+
+// productIndex returns the settings and mappings of the products index
+func productIndex(setID string) mapping.Index {
+	replicas := 1
+	return mapping.Index{
+		Settings: &mapping.IndexSettings{
+			NumberOfShards:   1,
+			NumberOfReplicas: &replicas,
+			Analysis:         productAnalysis(setID),
+		},
+		Mappings: catalog.ProductMapping,
+	}
+}
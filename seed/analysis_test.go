@@ -0,0 +1,45 @@
+package seed
+
+import (
+	"testing"
+
+	"Elastic-Search/catalog"
+	"Elastic-Search/mapping"
+)
+
+// builtinAnalyzers are the analyzers Elasticsearch defines itself that the mapping may use
+var builtinAnalyzers = map[string]bool{"standard": true, "simple": true, "whitespace": true, "keyword": true}
+
+// TestProductMappingReferencesDefinedAnalysis catches a renamed analyzer or normalizer whose
+// name is still spelled the old way in the es tags of catalog.Product
+func TestProductMappingReferencesDefinedAnalysis(t *testing.T) {
+	index := productIndex(DefaultSynonymSetID)
+	analysis := index.Settings.Analysis
+
+	var check func(path string, fields map[string]mapping.Field)
+	check = func(path string, fields map[string]mapping.Field) {
+		for name, field := range fields {
+			name = path + name
+			for _, analyzer := range []string{field.Analyzer, field.SearchAnalyzer} {
+				if _, ok := analysis.Analyzer[analyzer]; analyzer != "" && !ok && !builtinAnalyzers[analyzer] {
+					t.Errorf("%s uses analyzer %q, which the index settings do not define", name, analyzer)
+				}
+			}
+			if _, ok := analysis.Normalizer[field.Normalizer]; field.Normalizer != "" && !ok {
+				t.Errorf("%s uses normalizer %q, which the index settings do not define", name, field.Normalizer)
+			}
+			check(name+".", field.Fields)
+			check(name+".", field.Properties)
+		}
+	}
+	check("", index.Mappings.Properties)
+
+	for _, analyzer := range []string{catalog.EnglishAnalyzer, catalog.AutocompleteAnalyzer, catalog.SynonymAnalyzer} {
+		if _, ok := analysis.Analyzer[analyzer]; !ok {
+			t.Errorf("analyzer %q of package catalog is not defined", analyzer)
+		}
+	}
+	if _, ok := analysis.Normalizer[catalog.KeywordNormalizer]; !ok {
+		t.Errorf("normalizer %q of package catalog is not defined", catalog.KeywordNormalizer)
+	}
+}
@@ -0,0 +1,445 @@
+package seed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"slices"
+	"time"
+
+	"Elastic-Search/catalog"
+	"Elastic-Search/eserrors"
+	"Elastic-Search/logging"
+	"Elastic-Search/metrics"
+	"Elastic-Search/retry"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// ProductIndex is the default index of the seeder
+const ProductIndex = "products"
+
+// Config holds the settings of a Seeder
+type Config struct {
+	Index        string // Index to recreate, defaults to ProductIndex
+	SynonymSetID string // Synonym set of the search analyzer, defaults to DefaultSynonymSetID
+	SynonymsFile string // Optional: Synonyms in Solr format, defaults to DefaultSynonyms
+	BatchSize    int    // Products per bulk request, defaults to 100
+	// Optional: Retry policies, defaults to retry.DefaultPolicies(). Bulk requests index documents
+	// with explicit ids, so a repeated batch overwrites the same documents and is safe to retry.
+	Retry   *retry.Policies
+	Metrics *metrics.Metrics // Optional: Prometheus metrics for bulk and refresh requests
+	Logger  *slog.Logger     // Optional: Structured logger, defaults to slog.Default()
+}
+
+// Seeder recreates an index with the product mappings and fills it with generated products
+type Seeder struct {
+	client       *elasticsearch.Client
+	index        string
+	synonymSetID string
+	synonymsFile string
+	batchSize    int
+	retry        retry.Policies
+	metrics      *metrics.Metrics
+	logger       *slog.Logger
+}
+
+// NewSeeder creates a Seeder writing through client
+func NewSeeder(client *elasticsearch.Client, config Config) *Seeder {
+	s := &Seeder{
+		client:       client,
+		index:        config.Index,
+		synonymSetID: config.SynonymSetID,
+		synonymsFile: config.SynonymsFile,
+		batchSize:    config.BatchSize,
+		retry:        retry.DefaultPolicies(),
+		metrics:      config.Metrics,
+		logger:       logging.OrDefault(config.Logger),
+	}
+	if s.index == "" {
+		s.index = ProductIndex
+	}
+	if s.synonymSetID == "" {
+		s.synonymSetID = DefaultSynonymSetID
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = 100
+	}
+	if config.Retry != nil {
+		s.retry = *config.Retry
+	}
+	return s
+}
+
+// Index returns the index the seeder writes
+func (s *Seeder) Index() string {
+	return s.index
+}
+
+// Sample data arrays for generating random products
+var (
+	brands = []string{
+		"Apple", "Samsung", "Dell", "HP", "Lenovo", "Asus", "Acer", "Microsoft",
+		"LG", "Sony", "Intel", "AMD", "Razer", "MSI", "Toshiba",
+	}
+
+	categories = []string{
+		"Laptops", "Smartphones", "Tablets", "Desktops", "Monitors",
+		"Accessories", "Gaming", "Office", "Student", "Professional",
+	}
+
+	productTypes = []string{
+		"Laptop", "Smartphone", "Tablet", "Desktop", "Monitor",
+		"Keyboard", "Mouse", "Headphones", "Camera", "Printer",
+	}
+
+	adjectives = []string{
+		"Professional", "Gaming", "Ultra", "Premium", "Basic",
+		"Advanced", "Smart", "Portable", "Powerful", "Lightweight",
+	}
+
+	features = []string{
+		"4K Display", "Touch Screen", "Fast Charging", "Wireless",
+		"Bluetooth", "High Performance", "Long Battery Life",
+		"Ergonomic Design", "RGB Lighting", "Compact",
+	}
+)
+
+func generateProduct(id int) catalog.Product {
+	rand.Seed(time.Now().UnixNano())
+
+	// Generate random product name
+	productType := productTypes[rand.Intn(len(productTypes))]
+	adjective := adjectives[rand.Intn(len(adjectives))]
+	brand := brands[rand.Intn(len(brands))]
+	name := fmt.Sprintf("%s %s %s", brand, adjective, productType)
+
+	// Generate random description
+	feature1 := features[rand.Intn(len(features))]
+	feature2 := features[rand.Intn(len(features))]
+	description := fmt.Sprintf("A %s %s featuring %s and %s. Perfect for daily use.",
+		adjective, productType, feature1, feature2)
+
+	// Generate random categories (2-3 categories per product)
+	numCategories := rand.Intn(2) + 2
+	productCategories := make([]string, 0)
+	for i := 0; i < numCategories; i++ {
+		cat := categories[rand.Intn(len(categories))]
+		if !contains(productCategories, cat) {
+			productCategories = append(productCategories, cat)
+		}
+	}
+
+	// Generate random price between 100 and 3000
+	price := 100 + rand.Float64()*2900
+	price = float64(int(price*100)) / 100 // Round to 2 decimal places
+
+	return catalog.Product{
+		ID:          fmt.Sprintf("%d", id),
+		Name:        name,
+		Description: description,
+		Price:       price,
+		Categories:  productCategories,
+		Brand:       brand,
+		InStock:     rand.Float32() > 0.2, // 80% chance of being in stock
+		Rating:      1 + rand.Float64()*4, // Rating between 1 and 5
+		CreatedAt:   time.Now().Add(-time.Duration(rand.Intn(365)) * 24 * time.Hour),
+	}
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
+
+// Seed recreates the index with the product mappings and fills it with numProducts generated products
+func (s *Seeder) Seed(ctx context.Context, numProducts int) error {
+	indexName := s.index
+
+	// The synonym set must exist before an index analyzer can reference it. It is put before
+	// the index is deleted, so invalid synonyms leave the existing index in place.
+	rules, err := s.synonyms()
+	if err != nil {
+		return err
+	}
+	if err := s.putSynonymSet(ctx, rules); err != nil {
+		return err
+	}
+
+	if err := s.deleteIndex(ctx); err != nil {
+		return err
+	}
+
+	// Create index with mappings and analysis settings
+	jsonMappings, err := json.Marshal(productIndex(s.synonymSetID))
+	if err != nil {
+		return fmt.Errorf("error marshaling mappings: %w", err)
+	}
+
+	if err := s.createIndex(ctx, jsonMappings); err != nil {
+		return err
+	}
+
+	// Bulk indexing setup, each action is an action line followed by its source line
+	actions := make([][]byte, 0, s.batchSize)
+	for i := 1; i <= numProducts; i++ {
+		product := generateProduct(i)
+
+		// Create bulk action line
+		action := map[string]interface{}{
+			"index": map[string]interface{}{
+				"_id":    product.ID,
+				"_index": indexName,
+			},
+		}
+
+		actionJSON, err := json.Marshal(action)
+		if err != nil {
+			return fmt.Errorf("error marshaling action: %w", err)
+		}
+
+		// Add product data
+		productJSON, err := json.Marshal(product)
+		if err != nil {
+			return fmt.Errorf("error marshaling product: %w", err)
+		}
+		actions = append(actions, slices.Concat(actionJSON, []byte("\n"), productJSON, []byte("\n")))
+
+		// Execute bulk request every batch or on the last iteration
+		if len(actions) == s.batchSize || i == numProducts {
+			if err := s.flushBulk(ctx, actions); err != nil {
+				return err
+			}
+
+			// Reset the batch
+			actions = actions[:0]
+			s.logger.Info("indexed products", logging.KeyIndex, indexName, "count", i)
+		}
+	}
+
+	// Refresh the index
+	err = s.do(ctx, "refresh index", func(ctx context.Context) (*esapi.Response, error) {
+		return s.client.Indices.Refresh(
+			s.client.Indices.Refresh.WithContext(ctx),
+			s.client.Indices.Refresh.WithIndex(indexName),
+		)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("successfully indexed products", logging.KeyIndex, indexName, "count", numProducts)
+	return nil
+}
+
+// do sends an idempotent request with the retry policy of op, records it in the metrics and
+// checks its response, whose body is closed unread
+func (s *Seeder) do(ctx context.Context, op string, fn func(ctx context.Context) (*esapi.Response, error)) error {
+	start := time.Now()
+	res, err := retry.Do(ctx, s.retry.For(op, true), fn)
+	s.metrics.ObserveRequest("seed", op, s.index, start, res, err)
+	if err != nil {
+		return fmt.Errorf("error executing %s: %w", op, err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			s.logger.Warn("error closing body", logging.KeyError, err)
+		}
+	}(res.Body)
+
+	return eserrors.FromResponse(op, res)
+}
+
+// deleteIndex deletes the index if it exists
+func (s *Seeder) deleteIndex(ctx context.Context) error {
+	res, err := s.client.Indices.Delete(
+		[]string{s.index},
+		s.client.Indices.Delete.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("error deleting index: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			s.logger.Warn("error closing body", logging.KeyError, err)
+		}
+	}(res.Body)
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return eserrors.FromResponse("delete index", res)
+}
+
+// createIndex creates the index with body as settings and mappings. Rejected settings fail
+// here, before bulk indexing would create the index with a dynamic mapping instead.
+func (s *Seeder) createIndex(ctx context.Context, body []byte) error {
+	res, err := s.client.Indices.Create(
+		s.index,
+		s.client.Indices.Create.WithContext(ctx),
+		s.client.Indices.Create.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating index: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			s.logger.Warn("error closing body", logging.KeyError, err)
+		}
+	}(res.Body)
+
+	return eserrors.FromResponse("create index", res)
+}
+
+// bulkItem is the outcome of one action of a bulk request
+type bulkItem struct {
+	Status int `json:"status"`
+	Error  struct {
+		Type string `json:"type"`
+	} `json:"error"`
+}
+
+// flushBulk sends one batch of bulk actions. Transient failures of the whole request are
+// retried by retry.Do; items the cluster rejected with 429 because its queues were full are
+// sent again on their own, with the backoff of the same policy.
+func (s *Seeder) flushBulk(ctx context.Context, actions [][]byte) error {
+	policy := s.retry.For("bulk index", true)
+	attempts := max(policy.MaxAttempts, 1)
+
+	failures := map[string]int{}
+	pending := actions
+	for attempt := 1; len(pending) > 0; attempt++ {
+		items, err := s.sendBulk(ctx, pending)
+		if err != nil {
+			return err
+		}
+
+		var rejected [][]byte
+		for i, item := range items {
+			switch {
+			case item.Status < 300:
+			case item.Status == http.StatusTooManyRequests && attempt < attempts:
+				rejected = append(rejected, pending[i])
+			default:
+				failures[item.Error.Type]++
+			}
+		}
+		pending = rejected
+		if len(pending) == 0 {
+			break
+		}
+
+		wait := policy.Backoff(attempt)
+		s.logger.Warn("retrying bulk items rejected by the cluster",
+			logging.KeyOperation, "bulk index",
+			logging.KeyIndex, s.index,
+			"rejected", len(pending),
+			"attempt", attempt,
+			"wait", wait,
+		)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("bulk retry aborted after %d attempts: %w", attempt, ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	if len(failures) > 0 {
+		failed := 0
+		for _, count := range failures {
+			failed += count
+		}
+		s.logger.Warn("bulk request rejected items",
+			logging.KeyOperation, "bulk index",
+			logging.KeyIndex, s.index,
+			"failed", failed,
+			"items", len(actions),
+			"failures", failures,
+		)
+	}
+	s.metrics.ObserveBulk(s.index, len(actions), failures)
+	return nil
+}
+
+// sendBulk sends actions in one bulk request and returns the outcome of each, in order
+func (s *Seeder) sendBulk(ctx context.Context, actions [][]byte) ([]bulkItem, error) {
+	client, indexName := s.client, s.index
+	batch := bytes.Join(actions, nil)
+	start := time.Now()
+	res, err := retry.Do(ctx, s.retry.For("bulk index", true), func(ctx context.Context) (*esapi.Response, error) {
+		return client.Bulk(
+			bytes.NewReader(batch),
+			client.Bulk.WithContext(ctx),
+			client.Bulk.WithIndex(indexName),
+		)
+	})
+	s.metrics.ObserveRequest("seed", "bulk index", indexName, start, res, err)
+	s.logBulk(ctx, time.Since(start), len(actions), res, err)
+	if err != nil {
+		return nil, fmt.Errorf("error bulk indexing: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			s.logger.Warn("error closing body", logging.KeyError, err)
+		}
+	}(res.Body)
+
+	if err := eserrors.FromResponse("bulk index", res); err != nil {
+		return nil, err
+	}
+
+	// A successful bulk request can still reject individual items
+	var bulkResponse struct {
+		Items []map[string]bulkItem `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&bulkResponse); err != nil {
+		return nil, fmt.Errorf("error parsing bulk response: %w", err)
+	}
+	if len(bulkResponse.Items) != len(actions) {
+		return nil, fmt.Errorf("error parsing bulk response: %d items for %d actions", len(bulkResponse.Items), len(actions))
+	}
+
+	items := make([]bulkItem, 0, len(actions))
+	for _, item := range bulkResponse.Items {
+		// Each item is keyed by its action, e.g. "index"
+		for _, result := range item {
+			items = append(items, result)
+		}
+	}
+	return items, nil
+}
+
+// logBulk writes one line per bulk request with the fields shared by all Elasticsearch logs
+func (s *Seeder) logBulk(ctx context.Context, took time.Duration, items int, res *esapi.Response, err error) {
+	attrs := []slog.Attr{
+		slog.String(logging.KeyOperation, "bulk index"),
+		slog.String(logging.KeyIndex, s.index),
+		slog.Duration(logging.KeyTook, took),
+		slog.Int("items", items),
+	}
+	if res != nil {
+		attrs = append(attrs, slog.Int(logging.KeyStatus, res.StatusCode))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any(logging.KeyError, err))
+		s.logger.LogAttrs(ctx, slog.LevelError, "bulk request failed", attrs...)
+		return
+	}
+	s.logger.LogAttrs(ctx, slog.LevelDebug, "bulk request", attrs...)
+}
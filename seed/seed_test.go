@@ -0,0 +1,192 @@
+package seed
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"Elastic-Search/eserrors"
+	"Elastic-Search/internal/estest"
+	"Elastic-Search/retry"
+)
+
+// fakeBulkCluster answers bulk requests, rejecting the actions of an id with
+// status as often as rejects holds for the id
+type fakeBulkCluster struct {
+	status  int            // Status of the rejected items
+	rejects map[string]int // Times each id is rejected
+	sent    [][]string     // Ids of each bulk request
+}
+
+func (c *fakeBulkCluster) RoundTrip(r *http.Request) (*http.Response, error) {
+	var ids []string
+	var items []interface{}
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action map[string]struct {
+			ID string `json:"_id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+			return nil, err
+		}
+		scanner.Scan() // The source line
+		id := action["index"].ID
+		ids = append(ids, id)
+
+		item := map[string]interface{}{"_id": id, "status": http.StatusCreated}
+		if c.rejects[id] > 0 {
+			c.rejects[id]--
+			item = map[string]interface{}{
+				"_id":    id,
+				"status": c.status,
+				"error":  map[string]interface{}{"type": "es_rejected_execution_exception"},
+			}
+		}
+		items = append(items, map[string]interface{}{"index": item})
+	}
+	c.sent = append(c.sent, ids)
+
+	body, _ := json.Marshal(map[string]interface{}{"errors": true, "items": items})
+	return estest.Response(r, http.StatusOK, string(body)), nil
+}
+
+func newTestSeeder(t *testing.T, cluster http.RoundTripper) *Seeder {
+	t.Helper()
+	client := estest.NewClient(t, cluster)
+	policy := retry.DefaultPolicy()
+	policy.InitialBackoff, policy.MaxBackoff = time.Millisecond, time.Millisecond
+	return NewSeeder(client, Config{
+		Retry:  &retry.Policies{Default: policy},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+}
+
+func bulkActions(ids ...string) [][]byte {
+	actions := make([][]byte, 0, len(ids))
+	for _, id := range ids {
+		actions = append(actions, []byte(fmt.Sprintf("{\"index\":{\"_id\":%q}}\n{\"id\":%q}\n", id, id)))
+	}
+	return actions
+}
+
+func TestFlushBulkRetriesRejectedItems(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		rejects  map[string]int
+		wantSent [][]string
+	}{
+		{
+			"rejected items are sent again",
+			http.StatusTooManyRequests,
+			map[string]int{"2": 1, "3": 2},
+			[][]string{{"1", "2", "3"}, {"2", "3"}, {"3"}},
+		},
+		{
+			"items rejected on every attempt are given up",
+			http.StatusTooManyRequests,
+			map[string]int{"2": 10},
+			[][]string{{"1", "2", "3"}, {"2"}, {"2"}, {"2"}},
+		},
+		{
+			"other item failures are not retried",
+			http.StatusBadRequest,
+			map[string]int{"2": 1},
+			[][]string{{"1", "2", "3"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cluster := &fakeBulkCluster{status: test.status, rejects: test.rejects}
+			seeder := newTestSeeder(t, cluster)
+
+			if err := seeder.flushBulk(context.Background(), bulkActions("1", "2", "3")); err != nil {
+				t.Fatalf("flushBulk() error = %v", err)
+			}
+			if !slices.EqualFunc(cluster.sent, test.wantSent, slices.Equal) {
+				t.Errorf("sent %v, want %v", cluster.sent, test.wantSent)
+			}
+		})
+	}
+}
+
+// fakeIndexCluster answers every request with 200 unless statuses holds failures for
+// its method and path, and records the method and path of each request
+type fakeIndexCluster struct {
+	statuses map[string][]int // Statuses of the next requests to a method and path, e.g. "PUT /products"
+	sent     []string
+}
+
+func (c *fakeIndexCluster) RoundTrip(r *http.Request) (*http.Response, error) {
+	request := r.Method + " " + r.URL.Path
+	c.sent = append(c.sent, request)
+
+	status, body := http.StatusOK, `{"acknowledged": true}`
+	if statuses := c.statuses[request]; len(statuses) > 0 {
+		status, c.statuses[request] = statuses[0], statuses[1:]
+		body = fmt.Sprintf(`{"error": {"type": "test_exception", "reason": "failed"}, "status": %d}`, status)
+	}
+	return estest.Response(r, status, body), nil
+}
+
+func TestSeedOrder(t *testing.T) {
+	const (
+		putSynonyms = "PUT /_synonyms/products-synonyms"
+		deleteIndex = "DELETE /products"
+		createIndex = "PUT /products"
+		refresh     = "POST /products/_refresh"
+	)
+	tests := []struct {
+		name     string
+		statuses map[string][]int
+		wantErr  error
+		wantSent []string
+	}{
+		{
+			"synonyms are put before the index is recreated",
+			nil,
+			nil,
+			[]string{putSynonyms, deleteIndex, createIndex, refresh},
+		},
+		{
+			"rejected synonyms leave the index in place",
+			map[string][]int{putSynonyms: {http.StatusBadRequest}},
+			eserrors.ErrBadRequest,
+			[]string{putSynonyms},
+		},
+		{
+			"unavailable synonyms and refresh are retried",
+			map[string][]int{putSynonyms: {http.StatusServiceUnavailable}, refresh: {http.StatusServiceUnavailable}},
+			nil,
+			[]string{putSynonyms, putSynonyms, deleteIndex, createIndex, refresh, refresh},
+		},
+		{
+			"a failed refresh fails the seed",
+			map[string][]int{refresh: {http.StatusForbidden}},
+			eserrors.ErrUnauthorized,
+			[]string{putSynonyms, deleteIndex, createIndex, refresh},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cluster := &fakeIndexCluster{statuses: test.statuses}
+			seeder := newTestSeeder(t, cluster)
+
+			err := seeder.Seed(context.Background(), 0)
+			if !errors.Is(err, test.wantErr) || (err != nil) != (test.wantErr != nil) {
+				t.Fatalf("Seed() error = %v, want %v", err, test.wantErr)
+			}
+			if !slices.Equal(cluster.sent, test.wantSent) {
+				t.Errorf("sent %v, want %v", cluster.sent, test.wantSent)
+			}
+		})
+	}
+}
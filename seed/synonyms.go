@@ -0,0 +1,153 @@
+package seed
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"Elastic-Search/catalog"
+	"Elastic-Search/mapping"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// DefaultSynonymSetID is the synonym set referenced by the products index
+const DefaultSynonymSetID = "products-synonyms"
+
+// DefaultSynonyms are the rules of synonyms.txt, used when no synonyms file is configured
+//
+//go:embed synonyms.txt
+var DefaultSynonyms string
+
+// synonymFilterName is the filter that applies the synonym set in catalog.SynonymAnalyzer
+const synonymFilterName = "product_synonyms"
+
+// SynonymRule is a single rule of a synonym set, e.g. "laptop, notebook"
+type SynonymRule struct {
+	ID       string `json:"id,omitempty"`
+	Synonyms string `json:"synonyms"`
+}
+
+// LoadSynonyms reads synonym rules in Solr format from a local file
+func LoadSynonyms(path string) ([]SynonymRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening synonyms file: %w", err)
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	return ParseSynonyms(file)
+}
+
+// ParseSynonyms reads synonym rules in Solr format.
+// Blank lines and lines starting with # are ignored.
+func ParseSynonyms(r io.Reader) ([]SynonymRule, error) {
+	var rules []SynonymRule
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.Count(text, "=>") > 1 {
+			return nil, fmt.Errorf("invalid synonym rule on line %d: %q", line, text)
+		}
+		rules = append(rules, SynonymRule{
+			ID:       fmt.Sprintf("rule-%d", line),
+			Synonyms: text,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading synonyms: %w", err)
+	}
+	return rules, nil
+}
+
+// putSynonymSet creates or replaces the synonym set of the seeder through the synonyms API.
+// Indices whose analyzers reference the set pick up the new rules automatically.
+func (s *Seeder) putSynonymSet(ctx context.Context, rules []SynonymRule) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"synonyms_set": rules,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshaling synonym set: %w", err)
+	}
+
+	// Putting the same rules again leaves the same set, so the request is retried
+	return s.do(ctx, "put synonym set", func(ctx context.Context) (*esapi.Response, error) {
+		return s.client.SynonymsPutSynonym(
+			s.synonymSetID,
+			bytes.NewReader(body),
+			s.client.SynonymsPutSynonym.WithContext(ctx),
+		)
+	})
+}
+
+// reloadSearchAnalyzers reloads the updateable search analyzers of the index so
+// changed synonyms take effect without closing the index or reindexing.
+func (s *Seeder) reloadSearchAnalyzers(ctx context.Context) error {
+	return s.do(ctx, "reload search analyzers", func(ctx context.Context) (*esapi.Response, error) {
+		return s.client.Indices.ReloadSearchAnalyzers(
+			[]string{s.index},
+			s.client.Indices.ReloadSearchAnalyzers.WithContext(ctx),
+		)
+	})
+}
+
+// UpdateSynonyms replaces the synonym set of the seeder with its synonyms and reloads the
+// analyzers of its index, so changed rules apply to searches without reseeding
+func (s *Seeder) UpdateSynonyms(ctx context.Context) error {
+	rules, err := s.synonyms()
+	if err != nil {
+		return err
+	}
+	if err := s.putSynonymSet(ctx, rules); err != nil {
+		return err
+	}
+	return s.reloadSearchAnalyzers(ctx)
+}
+
+// synonyms returns the rules of the configured synonyms file, or DefaultSynonyms
+func (s *Seeder) synonyms() ([]SynonymRule, error) {
+	if s.synonymsFile == "" {
+		return ParseSynonyms(strings.NewReader(DefaultSynonyms))
+	}
+	return LoadSynonyms(s.synonymsFile)
+}
+
+// addSynonymAnalysis adds a search time synonym_graph analyzer backed by setID to analysis.
+// synonym_graph filters must be updateable to be reloaded, which restricts them to search analyzers.
+// The analyzer stems like the index analyzer so expanded synonyms match the indexed terms.
+func addSynonymAnalysis(analysis *mapping.Analysis, setID string) {
+	if analysis.Filter == nil {
+		analysis.Filter = map[string]interface{}{}
+	}
+	if analysis.Analyzer == nil {
+		analysis.Analyzer = map[string]mapping.Analyzer{}
+	}
+
+	analysis.Filter[synonymFilterName] = map[string]interface{}{
+		"type":         "synonym_graph",
+		"synonyms_set": setID,
+		"updateable":   true,
+	}
+	analysis.Analyzer[catalog.SynonymAnalyzer] = mapping.Analyzer{
+		Type:      "custom",
+		Tokenizer: "standard",
+		Filter: []string{
+			"lowercase",
+			"asciifolding",
+			synonymFilterName,
+			"english_possessive_stemmer",
+			"english_stop",
+			"english_stemmer",
+		},
+	}
+}
@@ -0,0 +1,111 @@
+// Package tracing creates OpenTelemetry spans for Elasticsearch calls and
+// propagates the trace context of the caller to the cluster.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"Elastic-Search/eserrors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "Elastic-Search"
+
+// Attribute keys set on every span, following the OpenTelemetry database conventions
+const (
+	AttrDBSystem    = attribute.Key("db.system")
+	AttrDBOperation = attribute.Key("db.operation")
+	AttrIndex       = attribute.Key("db.elasticsearch.path_parts.index")
+	AttrQueryKind   = attribute.Key("db.elasticsearch.query_kind")
+	AttrStatusCode  = attribute.Key("http.response.status_code")
+)
+
+// Start starts a client span for an Elasticsearch operation as a child of the span in ctx.
+// queryKind may be empty for operations that are not searches.
+func Start(ctx context.Context, operation, index, queryKind string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		AttrDBSystem.String("elasticsearch"),
+		AttrDBOperation.String(operation),
+		AttrIndex.String(index),
+	}
+	if queryKind != "" {
+		attrs = append(attrs, AttrQueryKind.String(queryKind))
+	}
+
+	name := operation
+	if queryKind != "" {
+		name = operation + " " + queryKind
+	}
+	return otel.Tracer(instrumentationName).Start(
+		ctx,
+		name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// End records the outcome of the operation on the span and ends it
+func End(span trace.Span, err error) {
+	var esErr *eserrors.ESError
+	if errors.As(err, &esErr) {
+		span.SetAttributes(AttrStatusCode.Int(esErr.Status))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Transport injects the trace context of each request's context into its headers
+// (traceparent/tracestate) so Elasticsearch can join the caller's trace.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	propagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	return t.next.RoundTrip(req)
+}
+
+// propagator falls back to W3C trace context when no global propagator was configured
+func propagator() propagation.TextMapPropagator {
+	p := otel.GetTextMapPropagator()
+	if len(p.Fields()) == 0 {
+		return propagation.TraceContext{}
+	}
+	return p
+}
+
+// InstallInMemory sets a global tracer provider that keeps finished spans in memory,
+// so tests and demos can assert on the spans that were created.
+// Call the returned function to restore the previous provider.
+func InstallInMemory() (*tracetest.InMemoryExporter, func()) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	return exporter, func() {
+		_ = provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previous)
+	}
+}
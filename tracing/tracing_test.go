@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Elastic-Search/eserrors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func installInMemory(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter, restore := InstallInMemory()
+	t.Cleanup(restore)
+	return exporter
+}
+
+// attributes returns the attributes of span by key
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, attr := range span.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	return attrs
+}
+
+func TestStartEnd(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		queryKind string
+		err       error
+		wantName  string
+		wantCode  codes.Code
+	}{
+		{"search", "search", "match", nil, "search match", codes.Unset},
+		{"operation without query kind", "get user", "", nil, "get user", codes.Unset},
+		{
+			"elasticsearch error",
+			"search", "fuzzy",
+			&eserrors.ESError{Op: "search", Status: http.StatusTooManyRequests, Type: "es_rejected_execution_exception"},
+			"search fuzzy", codes.Error,
+		},
+		{"client error", "bulk", "", errors.New("connection refused"), "bulk", codes.Error},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exporter := installInMemory(t)
+
+			_, span := Start(context.Background(), test.operation, "products", test.queryKind)
+			End(span, test.err)
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("recorded %d spans, want 1", len(spans))
+			}
+			got := spans[0]
+			if got.Name != test.wantName {
+				t.Errorf("name = %q, want %q", got.Name, test.wantName)
+			}
+			if got.Status.Code != test.wantCode {
+				t.Errorf("status = %v, want %v", got.Status.Code, test.wantCode)
+			}
+
+			attrs := attributes(got)
+			for key, want := range map[attribute.Key]string{
+				AttrDBSystem:    "elasticsearch",
+				AttrDBOperation: test.operation,
+				AttrIndex:       "products",
+			} {
+				if attrs[key].AsString() != want {
+					t.Errorf("%s = %q, want %q", key, attrs[key].AsString(), want)
+				}
+			}
+			if kind, ok := attrs[AttrQueryKind]; ok != (test.queryKind != "") || kind.AsString() != test.queryKind {
+				t.Errorf("%s = %q, want %q", AttrQueryKind, kind.AsString(), test.queryKind)
+			}
+
+			var esErr *eserrors.ESError
+			if errors.As(test.err, &esErr) && attrs[AttrStatusCode].AsInt64() != int64(esErr.Status) {
+				t.Errorf("%s = %d, want %d", AttrStatusCode, attrs[AttrStatusCode].AsInt64(), esErr.Status)
+			}
+		})
+	}
+}
+
+func TestTransportPropagatesTraceContext(t *testing.T) {
+	installInMemory(t)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, span := Start(context.Background(), "search", "products", "match")
+	defer span.End()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	// traceparent is version-traceid-spanid-flags
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 {
+		t.Fatalf("traceparent = %q, want the W3C format", traceparent)
+	}
+	spanContext := span.SpanContext()
+	if parts[1] != spanContext.TraceID().String() || parts[2] != spanContext.SpanID().String() {
+		t.Errorf("traceparent = %q, want trace %s and parent %s", traceparent, spanContext.TraceID(), spanContext.SpanID())
+	}
+	if req.Header.Get("traceparent") != "" {
+		t.Error("Transport modified the caller's request")
+	}
+}